@@ -2,39 +2,109 @@ package bee
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 	"net/http"
+	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // MagicApp 魔术
 type MagicApp struct {
-	Addr           string              // 运行地址端口
-	ConfPath       string              // 配置文件路径
-	ConfName       string              // 配置文件名
-	ConfHotLoading bool                // 配置启用热加载
-	IsDefault      bool                // 是否使用默认路由引擎
-	IsHeartbeat    bool                // 开启心跳检测, 默认关闭
-	RunMode        string              // 运行模式
-	RegRouteFun    func(r *gin.Engine) // 路由注册
-	ExitAfter      func()              // 程序结束后的操作
-	Router         *gin.Engine
-	isInit         bool // 是否初始化过
+	Addr             string              // 运行地址端口
+	AdminAddr        string              // 管理路由地址端口, 为空时不启动, 未指定主机时(如:9090)仅监听127.0.0.1, 对外暴露需显式指定如0.0.0.0:9090
+	UnixSocket       string              // Unix套接字路径, 为空时不启动
+	ConfPath         string              // 配置文件路径
+	ConfName         string              // 配置文件名
+	ConfHotLoading   bool                // 配置启用热加载
+	EnvOverride      bool                // 启用环境变量覆盖已有配置, 如DBCLIENT_MAIN_PASSWD覆盖dbClient.main.passwd, 未设置前缀时不覆盖顶级key
+	EnvPrefix        string              // 环境变量前缀, 设置后启用覆盖, 带前缀的环境变量可新增配置
+	EnvKeyReplacer   *strings.Replacer   // 配置key转换为环境变量名的规则, 默认将.和-替换为_
+	EnvFile          string              // .env文件路径, 为空时不加载
+	MasterKeyFile    string              // 配置密文主密钥文件路径, 为空时读取环境变量BEE_MASTER_KEY或BEE_MASTER_KEY_FILE
+	ConfDebounce     time.Duration       // 配置热加载防抖时间, 默认500ms
+	ConfigSources    []ConfigSource      // 额外配置源(HTTP、键值存储等), 按顺序覆盖配置文件, 环境变量覆盖优先级最高
+	IsDefault        bool                // 是否使用默认路由引擎
+	IsHeartbeat      bool                // 开启心跳检测, 默认关闭
+	RunMode          string              // 运行模式
+	ShutdownTimeout  time.Duration       // 优雅关闭超时时间, 默认5s
+	PreStopDelay     time.Duration       // 关闭前的等待时间, 期间就绪探针返回未就绪, 请求继续处理
+	GracefulRestart  bool                // 开启平滑重启, 收到SIGUSR2/SIGHUP时启动新进程接管监听后退出
+	RestartTimeout   time.Duration       // 平滑重启等待新进程就绪的超时时间, 默认30s
+	RegRouteFun      func(r *gin.Engine) // 路由注册
+	RegAdminRouteFun func(r *gin.Engine) // 管理路由注册
+	ExitAfter        func()              // 程序结束后的操作
+	Router           *gin.Engine
+	AdminRouter      *gin.Engine // 管理路由引擎
+	isInit           bool        // 是否初始化过
+	initErr          error       // 初始化失败的错误, 失败后不可再次初始化
+	lifecycle        lifecycle   // 组件生命周期
+	drain            drainState  // 请求排空状态
+	tlsConfig        *tls.Config // TLS配置, 为空时使用HTTP
+	isolated         bool        // 是否为独立应用, 非独立应用初始化时成为默认应用
+	placeholder      bool        // 是否为初始化前Default()创建的占位应用
+	mu               sync.RWMutex
+	conf             *viper.Viper       // 配置实例, 每次加载生成新实例
+	baseConf         *viper.Viper       // 基础配置层
+	confLayers       []*configLayer     // 配置层: 配置文件(基础、环境、本地)及配置源
+	confSources      map[string]string  // 配置key的来源
+	confSecrets      map[string]bool    // 值来自引用或密文的配置key
+	confWatch        configWatch        // 配置变更订阅
+	logger           *zap.SugaredLogger // 日志实例
+	extMu            sync.Mutex
+	extensions       map[string]any // 应用扩展
+	modules          []Module       // 功能模块
+	log              *MagicLog      // 日志
+	healthMu         sync.Mutex
+	healthChecks     []*HealthCheck // 健康检查项
+	metrics          metrics        // 指标
 }
 
-// Init 初始化
-func (m *MagicApp) Init() {
+// Init 初始化, 已初始化时直接返回
+// 初始化失败后路由、配置订阅等已部分注册, 不支持重试, 再次调用返回ErrInitFailed, 需重新创建应用
+func (m *MagicApp) Init() (err error) {
+	if m.isInit {
+		return nil
+	}
+	if m.initErr != nil {
+		return newAppError(StageInit, fmt.Errorf("%w: %w", ErrInitFailed, m.initErr))
+	}
+	defer func() { m.initErr = err }()
+	if !m.isolated {
+		// 接管初始化前通过包级函数注册在占位应用上的配置订阅
+		if prev := defaultApp.Swap(m); prev != nil && prev != m && prev.placeholder {
+			m.adoptConfigWatch(prev)
+		}
+	}
 	// 初始化路由引擎
 	m.initRouter()
+	m.Router.Use(m.bindApp(), m.trackRequests())
 	// 初始化配置文件
-	m.initConfig()
+	if err := m.initConfig(); err != nil {
+		return newAppError(StageConfig, err)
+	}
 	// 初始化日志
-	m.initLog()
+	if err := m.initLog(); err != nil {
+		return newAppError(StageLog, err)
+	}
+	// 初始化TLS
+	if err := m.initTLS(); err != nil {
+		return newAppError(StageConfig, err)
+	}
+	// 启动组件
+	if err := m.lifecycle.start(context.Background()); err != nil {
+		return newAppError(StageComponent, err)
+	}
 	// 心跳检测
 	if m.IsHeartbeat {
 		m.testRoute()
@@ -43,42 +113,107 @@ func (m *MagicApp) Init() {
 	if m.RegRouteFun != nil {
 		m.RegRouteFun(m.Router)
 	}
+	// 模块挂载
+	if err := m.initModules(); err != nil {
+		_ = m.lifecycle.stop(context.Background())
+		return newAppError(StageModule, err)
+	}
+	// 管理路由注册
+	m.initAdminRouter()
 	// 初始化完毕
 	m.isInit = true
 
+	return nil
 }
 
-func (m *MagicApp) Run() {
+// Run 运行服务, 收到退出信号后优雅关闭
+func (m *MagicApp) Run() error {
 	if !m.isInit {
-		fmt.Printf("[%s] server start err: 未初始化数据\n", time.Now().Format(time.DateTime))
-		return
+		return newAppError(StageInit, ErrNotInit)
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	srv := &http.Server{Addr: fmt.Sprintf("%s", m.Addr), Handler: m.Router}
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			fmt.Printf("[%s] server listen err: %s\n", time.Now().Format(time.DateTime), err)
-		}
-	}()
+	servers, err := m.servers()
+	if err != nil {
+		errs := append([]error{newAppError(StageListen, err)}, m.teardown(context.Background())...)
+		return errors.Join(errs...)
+	}
+	listenErr := make(chan error, 1)
+	for _, s := range servers {
+		s.serve(listenErr)
+	}
+	m.drain.ready.Store(true)
+	notifyParentReady()
 
-	<-ctx.Done()
+	restartCh := make(chan os.Signal, 1)
+	if m.GracefulRestart && len(restartSignals) > 0 {
+		signal.Notify(restartCh, restartSignals...)
+		defer signal.Stop(restartCh)
+	}
+
+	var errs []error
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			break wait
+		case err := <-listenErr:
+			errs = append(errs, newAppError(StageListen, err))
+			break wait
+		case <-restartCh:
+			if err := m.restart(ctx, servers); err != nil {
+				fmt.Printf("[%s] graceful restart err: %s\n", time.Now().Format(time.DateTime), err)
+				continue
+			}
+			break wait
+		}
+	}
+	cancelForce := forceExitOnSignal()
+	defer cancelForce()
 	stop()
+	m.drain.ready.Store(false)
 
 	fmt.Println("shutting down gracefully, press Ctrl+C again to force")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if m.PreStopDelay > 0 && len(errs) == 0 {
+		time.Sleep(m.PreStopDelay)
+	}
+	timeout := m.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		fmt.Println("Server forced to shutdown: ", err)
+	for _, s := range servers {
+		if err := s.srv.Shutdown(ctx); err != nil {
+			errs = append(errs, newAppError(StageShutdown, fmt.Errorf("<%s>%w", s.name, err)))
+		}
+	}
+	if err := m.waitDrain(ctx); err != nil {
+		errs = append(errs, newAppError(StageShutdown, err))
 	}
+	errs = append(errs, m.teardown(ctx)...)
+
+	fmt.Println("Server exiting")
+	return errors.Join(errs...)
+}
+
+// teardown 逆序停止组件、停止配置监听、执行ExitAfter并写完日志
+func (m *MagicApp) teardown(ctx context.Context) []error {
+	var errs []error
+	if err := m.lifecycle.stop(ctx); err != nil {
+		errs = append(errs, newAppError(StageComponent, err))
+	}
+	m.stopWatchSources()
 
 	if m.ExitAfter != nil {
 		m.ExitAfter()
 	}
+	// 写完缓冲及异步队列中的日志
+	_ = m.Logger().Sync()
 
-	fmt.Println("Server exiting")
+	return errs
 }
 
 // initRouter 初始化路由引擎
@@ -97,34 +232,50 @@ func (m *MagicApp) initRouter() {
 	}
 }
 
-// initConfig 初始化配置
-func (m *MagicApp) initConfig() {
-	if m.ConfPath == "" || m.ConfName == "" {
-		fmt.Printf("[%s] 初始化配置...跳过\n", time.Now().Format(time.DateTime))
-		return
+// InitLog 初始化日志
+func (m *MagicApp) initLog() error {
+	dropped := map[string]*Counter{
+		DropSampling: m.Counter("bee_log_dropped_total", "Log entries dropped by sampling."),
+		DropAsync:    m.Counter("bee_log_async_dropped_total", "Log entries dropped by async queue overflow."),
+		DropRemote:   m.Counter("bee_log_remote_dropped_total", "Log entries dropped by remote outputs."),
 	}
-	viper.SetConfigName(m.ConfName)
-	viper.AddConfigPath(m.ConfPath)
-	if err := viper.ReadInConfig(); err != nil {
-		panic("初始化配置失败")
+	magicLog := &MagicLog{LogKey: "app.log", conf: m.Config(), OnDrop: func(reason string) { dropped[reason].Inc() }}
+	logger, err := magicLog.initLogger()
+	if err != nil {
+		return err
 	}
-	fmt.Printf("[%s] 初始化配置...ok\n", time.Now().Format(time.DateTime))
-	if m.ConfHotLoading {
-		//viper.OnConfigChange(func(e fsnotify.Event) {})  // 配置变化时的回调
-		viper.WatchConfig()
+	m.setLogger(logger)
+	m.log = magicLog
+	// 包级Logger仅在初始化时设置, 始终写入应用当前日志
+	if defaultApp.Load() == m {
+		Logger = zap.New(&appCore{app: m}, zap.AddStacktrace(zapcore.ErrorLevel)).Sugar()
 	}
-}
-
-// InitLog 初始化日志
-func (m *MagicApp) initLog() {
-	magicLog := &MagicLog{LogKey: "app.log"}
-	magicLog.initLogger()
+	// 日志配置变更时调整级别或重建日志
+	WatchConfigApply(m, magicLog.LogKey, func(_, new *logConfig) error {
+		logger, err := magicLog.reconfigure(new)
+		if err != nil {
+			return err
+		}
+		if logger != nil {
+			m.setLogger(logger)
+		}
+		return nil
+	})
+	// 日志目录磁盘空间检查
+	minDiskFree := m.Config().GetUint64("app.health.minDiskFree")
+	if minDiskFree == 0 {
+		minDiskFree = defaultMinDiskFree
+	}
+	m.AddHealthCheck(diskCheck(magicLog.cfg.FilePath, minDiskFree))
 	fmt.Printf("[%s] 初始化日志...ok\n", time.Now().Format(time.DateTime))
+	return nil
 }
 
-// testRoute 心跳检测
+// testRoute 心跳检测、存活探针、就绪探针
 func (m *MagicApp) testRoute() {
 	m.Router.GET("/ping", func(c *gin.Context) {
 		c.String(http.StatusOK, "success! This service is normal.")
 	})
+	m.Router.GET("/healthz", m.healthHandler)
+	m.Router.GET("/readyz", m.readyHandler)
 }
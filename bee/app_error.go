@@ -0,0 +1,46 @@
+package bee
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 启动/运行阶段
+const (
+	StageInit      = "init"      // 初始化
+	StageConfig    = "config"    // 加载配置
+	StageLog       = "log"       // 初始化日志
+	StageComponent = "component" // 组件启动/停止
+	StageModule    = "module"    // 模块初始化
+	StageListen    = "listen"    // 端口监听
+	StageShutdown  = "shutdown"  // 优雅关闭
+)
+
+var (
+	ErrNotInit       = errors.New("未初始化数据")
+	ErrConfigMissing = errors.New("配置文件不存在")
+	ErrConfigInvalid = errors.New("配置文件解析失败")
+	ErrInitFailed    = errors.New("初始化已失败, 不可重试")
+)
+
+// AppError 应用启动/运行错误
+type AppError struct {
+	Stage string // 出错阶段
+	Err   error  // 原始错误
+}
+
+func (e *AppError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Stage, e.Err)
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// newAppError 包装阶段错误, err为空时返回nil
+func newAppError(stage string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{Stage: stage, Err: err}
+}
@@ -0,0 +1,181 @@
+package bee
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// testViper 日志写入临时目录的配置
+func testViper(t *testing.T, settings map[string]any) *viper.Viper {
+	conf := viper.New()
+	conf.Set("app.log.filePath", t.TempDir())
+	conf.Set("app.log.outputs", []map[string]any{{"type": OutputFile}})
+	for k, v := range settings {
+		conf.Set(k, v)
+	}
+	return conf
+}
+
+func TestAppErrorStage(t *testing.T) {
+	errBoom := errors.New("boom")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("a: [1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		opts   func(t *testing.T) []Option
+		run    bool // 是否跳过Init直接Run
+		stage  string
+		target error
+	}{
+		{
+			name:   "run before init",
+			opts:   func(t *testing.T) []Option { return nil },
+			run:    true,
+			stage:  StageInit,
+			target: ErrNotInit,
+		},
+		{
+			name:   "missing config",
+			opts:   func(t *testing.T) []Option { return []Option{WithConfig(dir, "missing")} },
+			stage:  StageConfig,
+			target: ErrConfigMissing,
+		},
+		{
+			name:   "invalid config",
+			opts:   func(t *testing.T) []Option { return []Option{WithConfig(dir, "bad")} },
+			stage:  StageConfig,
+			target: ErrConfigInvalid,
+		},
+		{
+			name: "invalid log level",
+			opts: func(t *testing.T) []Option {
+				return []Option{WithViper(testViper(t, map[string]any{"app.log.level": "loud"}))}
+			},
+			stage: StageLog,
+		},
+		{
+			name: "component start failed",
+			opts: func(t *testing.T) []Option {
+				return []Option{
+					WithViper(testViper(t, nil)),
+					WithComponents(&Component{Name: "broken", Start: func(context.Context) error { return errBoom }}),
+				}
+			},
+			stage:  StageComponent,
+			target: errBoom,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(tt.opts(t)...)
+			var err error
+			if tt.run {
+				err = app.Run()
+			} else {
+				err = app.Init()
+			}
+			var appErr *AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("err = %v, want *AppError", err)
+			}
+			if appErr.Stage != tt.stage {
+				t.Errorf("stage = %s, want %s", appErr.Stage, tt.stage)
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("err = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestRunListenErrorTearsDown(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	var stopped, exited bool
+	app := New(
+		WithViper(testViper(t, nil)),
+		WithAddr(busy.Addr().String()),
+		WithComponents(&Component{Name: "pool", Stop: func(context.Context) error { stopped = true; return nil }}),
+		WithExitAfter(func() { exited = true }),
+	)
+	if err = app.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.Run()
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Stage != StageListen {
+		t.Fatalf("err = %v, want listen stage", err)
+	}
+	if !stopped || !exited {
+		t.Errorf("stopped = %v, exited = %v, want components stopped and ExitAfter called", stopped, exited)
+	}
+}
+
+func TestInitTwice(t *testing.T) {
+	app := New(WithViper(testViper(t, nil)))
+	for i := 0; i < 2; i++ {
+		if err := app.Init(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(app.Router.Handlers); n != 2 {
+		t.Errorf("router middlewares = %d, want 2", n)
+	}
+}
+
+// flakyModule 首次初始化失败的模块
+type flakyModule struct {
+	BaseModule
+	calls int
+}
+
+func (f *flakyModule) Name() string { return "flaky" }
+
+func (f *flakyModule) Init(*MagicApp) error {
+	f.calls++
+	if f.calls == 1 {
+		return errors.New("not ready")
+	}
+	return nil
+}
+
+func (f *flakyModule) Routes(g *gin.RouterGroup) {
+	g.GET("/flaky", func(c *gin.Context) {})
+}
+
+func TestInitRetryAfterModuleFailure(t *testing.T) {
+	mod := &flakyModule{}
+	app := New(WithViper(testViper(t, nil)), WithHeartbeat(), WithModules(mod))
+	err := app.Init()
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Stage != StageModule {
+		t.Fatalf("first Init() = %v, want module stage error", err)
+	}
+
+	// 失败后不可重试, 不重复注册路由及配置订阅
+	err = app.Init()
+	if !errors.As(err, &appErr) || appErr.Stage != StageInit || !errors.Is(err, ErrInitFailed) {
+		t.Fatalf("second Init() = %v, want ErrInitFailed", err)
+	}
+	if mod.calls != 1 {
+		t.Errorf("module Init calls = %d, want 1", mod.calls)
+	}
+	if err = app.Run(); !errors.Is(err, ErrNotInit) {
+		t.Errorf("Run() = %v, want ErrNotInit", err)
+	}
+}
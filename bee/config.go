@@ -0,0 +1,405 @@
+package bee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultConfDebounce 默认配置热加载防抖时间
+const defaultConfDebounce = 500 * time.Millisecond
+
+// configSubscriber 配置变更订阅
+type configSubscriber struct {
+	key      string                   // 配置key, 为空表示整个配置
+	validate func(new any) error      // 应用前校验
+	apply    func(old, new any) error // 变更回调, 返回错误时回滚本次加载
+}
+
+// configLayer 配置层
+type configLayer struct {
+	name string       // 来源名称
+	src  ConfigSource // 配置源
+	mu   sync.RWMutex
+	data map[string]any // 最近一次加载成功的配置
+}
+
+// configWatch 配置变更订阅管理
+type configWatch struct {
+	reloadMu    sync.Mutex // 串行执行重新加载
+	mu          sync.Mutex
+	subscribers []*configSubscriber
+	timer       *time.Timer        // 防抖定时器
+	cancel      context.CancelFunc // 停止配置源监听
+}
+
+// initConfig 初始化配置: 加载.env文件、读取配置文件及配置源、环境变量覆盖
+func (m *MagicApp) initConfig() error {
+	if m.EnvFile != "" {
+		if err := loadDotEnv(m.EnvFile); err != nil {
+			return fmt.Errorf("加载.env文件失败: %w", err)
+		}
+	}
+	useEnv := m.EnvOverride || m.EnvPrefix != ""
+	useFile := m.ConfPath != "" && m.ConfName != ""
+	if !useFile && !useEnv && len(m.ConfigSources) == 0 {
+		fmt.Printf("[%s] 初始化配置...跳过\n", time.Now().Format(time.DateTime))
+		return nil
+	}
+
+	ctx := context.Background()
+	var sources []ConfigSource
+	if useFile {
+		// 基础配置文件必须存在, 环境配置(如app.release.yaml)与本地配置(app.local.yaml)可选
+		names := []string{m.ConfName, m.ConfName + ".local"}
+		if m.RunMode != "" {
+			names = []string{m.ConfName, m.ConfName + "." + m.RunMode, m.ConfName + ".local"}
+		}
+		for i, name := range names {
+			fileConf := viper.New()
+			fileConf.SetConfigName(name)
+			fileConf.AddConfigPath(m.ConfPath)
+			if err := fileConf.ReadInConfig(); err != nil {
+				var notFound viper.ConfigFileNotFoundError
+				if errors.As(err, &notFound) {
+					if i == 0 {
+						return fmt.Errorf("%w: %w", ErrConfigMissing, err)
+					}
+					continue
+				}
+				return fmt.Errorf("%w: %w", ErrConfigInvalid, err)
+			}
+			sources = append(sources, NewFileSource(fileConf.ConfigFileUsed()))
+		}
+	}
+	for _, src := range append(sources, m.ConfigSources...) {
+		data, err := src.Load(ctx)
+		if err != nil {
+			if _, ok := src.(*FileSource); ok {
+				return fmt.Errorf("%w: %w", ErrConfigInvalid, err)
+			}
+			return fmt.Errorf("<%s>加载配置失败: %w", src.Name(), err)
+		}
+		m.confLayers = append(m.confLayers, &configLayer{name: src.Name(), src: src, data: data})
+	}
+	conf, confSources, secrets, err := m.buildConfig()
+	if err != nil {
+		return err
+	}
+	m.setConfig(conf, confSources, secrets)
+	if m.ConfHotLoading {
+		m.watchSources()
+	}
+	fmt.Printf("[%s] 初始化配置...ok\n", time.Now().Format(time.DateTime))
+
+	return nil
+}
+
+// watchSources 监听支持变更通知的配置源, 变更后更新配置层并触发重新加载
+func (m *MagicApp) watchSources() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.confWatch.mu.Lock()
+	m.confWatch.cancel = cancel
+	m.confWatch.mu.Unlock()
+	for _, layer := range m.confLayers {
+		src, ok := layer.src.(WatchableSource)
+		if !ok {
+			continue
+		}
+		go func(layer *configLayer, src WatchableSource) {
+			err := src.Watch(ctx, func(data map[string]any, err error) {
+				if err != nil {
+					// 加载失败时保留该配置层的原配置
+					m.Logger().Errorf("<%s>配置源加载失败: %s", layer.name, err)
+					return
+				}
+				layer.mu.Lock()
+				layer.data = data
+				layer.mu.Unlock()
+				m.scheduleReload()
+			})
+			if err != nil {
+				m.Logger().Errorf("<%s>配置源监听失败: %s", layer.name, err)
+			}
+		}(layer, src)
+	}
+}
+
+// stopWatchSources 停止配置源监听
+func (m *MagicApp) stopWatchSources() {
+	m.confWatch.mu.Lock()
+	defer m.confWatch.mu.Unlock()
+	if m.confWatch.cancel != nil {
+		m.confWatch.cancel()
+		m.confWatch.cancel = nil
+	}
+	if m.confWatch.timer != nil {
+		m.confWatch.timer.Stop()
+	}
+}
+
+// buildConfig 按基础配置、配置文件(基础、环境、本地)、配置源、环境变量的顺序合并生成新的配置实例, 最后解析引用及密文
+// map深度合并, 列表及其他值整体替换
+func (m *MagicApp) buildConfig() (*viper.Viper, map[string]string, map[string]bool, error) {
+	conf := viper.New()
+	sources := make(map[string]string)
+	layers := m.confLayers
+	if m.baseConf != nil {
+		layers = append([]*configLayer{{name: "base", data: m.baseConf.AllSettings()}}, layers...)
+	}
+	for _, layer := range layers {
+		layer.mu.RLock()
+		layerConf := viper.New()
+		err := layerConf.MergeConfigMap(layer.data)
+		layer.mu.RUnlock()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err = conf.MergeConfigMap(layerConf.AllSettings()); err != nil {
+			return nil, nil, nil, err
+		}
+		for _, key := range layerConf.AllKeys() {
+			sources[key] = layer.name
+		}
+	}
+	envSources, err := m.applyEnv(conf)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("环境变量覆盖配置失败: %w", err)
+	}
+	for key, name := range envSources {
+		sources[key] = name
+	}
+	// 解析引用及密文
+	resolved, secrets, err := m.resolveSecrets(conf.AllSettings())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("解析配置引用失败: %w", err)
+	}
+	conf = viper.New()
+	if err = conf.MergeConfigMap(resolved); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return conf, sources, secrets, nil
+}
+
+// ConfigSource 配置key的来源(配置文件路径或env:环境变量名)
+func (m *MagicApp) ConfigSource(key string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.confSources[strings.ToLower(key)]
+}
+
+// DumpConfig 输出合并后的有效配置及每个key的来源, 敏感字段、环境变量及引用或密文解析出的值脱敏
+func (m *MagicApp) DumpConfig(w io.Writer) error {
+	conf := m.Config()
+	keys := conf.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := fmt.Sprint(conf.Get(key))
+		source := m.ConfigSource(key)
+		if value != "" && (isSecretKey(key) || strings.HasPrefix(source, "env:") || m.isResolvedSecret(key)) {
+			value = "******"
+		}
+		if source == "" {
+			source = "unknown"
+		}
+		if _, err := fmt.Fprintf(w, "%s = %s\t# %s\n", key, value, source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isSecretKey 是否为敏感配置key
+func isSecretKey(key string) bool {
+	last := key[strings.LastIndex(key, ".")+1:]
+	for _, word := range []string{"passwd", "password", "secret", "token", "key"} {
+		if strings.Contains(last, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// isResolvedSecret 配置值是否来自引用或密文
+func (m *MagicApp) isResolvedSecret(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.confSecrets[key]
+}
+
+// setConfig 替换配置实例、配置来源及引用或密文解析出的key
+func (m *MagicApp) setConfig(conf *viper.Viper, sources map[string]string, secrets map[string]bool) {
+	m.mu.Lock()
+	m.conf, m.confSources, m.confSecrets = conf, sources, secrets
+	m.mu.Unlock()
+}
+
+// scheduleReload 防抖后重新加载配置
+func (m *MagicApp) scheduleReload() {
+	debounce := m.ConfDebounce
+	if debounce <= 0 {
+		debounce = defaultConfDebounce
+	}
+	w := &m.confWatch
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(debounce, func() {
+		if err := m.ReloadConfig(); err != nil {
+			m.Logger().Error(err)
+		}
+	})
+}
+
+// ReloadConfig 重新加载配置, 变更的配置项全部校验通过后才生效并通知订阅者, 否则保留原配置
+// 订阅者应用失败时恢复原配置, 并以原配置通知已应用的订阅者
+func (m *MagicApp) ReloadConfig() error {
+	w := &m.confWatch
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	conf, sources, secrets, err := m.buildConfig()
+	if err != nil {
+		return fmt.Errorf("重新加载配置失败: %w", err)
+	}
+	m.mu.RLock()
+	old, oldSources, oldSecrets := m.conf, m.confSources, m.confSecrets
+	m.mu.RUnlock()
+	if old == nil {
+		old = viper.New()
+	}
+
+	w.mu.Lock()
+	subscribers := append([]*configSubscriber(nil), w.subscribers...)
+	w.mu.Unlock()
+
+	type change struct {
+		sub      *configSubscriber
+		old, new any
+	}
+	var changes []change
+	var errs []error
+	for _, sub := range subscribers {
+		oldValue, newValue := configValue(old, sub.key), configValue(conf, sub.key)
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if sub.validate != nil {
+			if err = sub.validate(newValue); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		changes = append(changes, change{sub, oldValue, newValue})
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("新配置校验失败, 保留原配置: %w", errors.Join(errs...))
+	}
+
+	m.setConfig(conf, sources, secrets)
+	for i, c := range changes {
+		if c.sub.apply == nil {
+			continue
+		}
+		if err = c.sub.apply(c.old, c.new); err != nil {
+			m.setConfig(old, oldSources, oldSecrets)
+			errs = append(errs, err)
+			for j := i - 1; j >= 0; j-- {
+				if r := changes[j]; r.sub.apply != nil {
+					if err := r.sub.apply(r.new, r.old); err != nil {
+						errs = append(errs, fmt.Errorf("<%s>回滚失败: %w", r.sub.key, err))
+					}
+				}
+			}
+			return fmt.Errorf("新配置应用失败, 已恢复原配置: %w", errors.Join(errs...))
+		}
+	}
+	fmt.Printf("[%s] 重新加载配置...ok\n", time.Now().Format(time.DateTime))
+
+	return nil
+}
+
+// configValue 获取配置值, key为空时返回整个配置
+func configValue(conf *viper.Viper, key string) any {
+	if key == "" {
+		return conf.AllSettings()
+	}
+	return conf.Get(key)
+}
+
+// OnConfigChange 订阅配置变更, key对应的配置变化时回调, 为空表示整个配置
+func (m *MagicApp) OnConfigChange(key string, fn func(old, new any)) {
+	m.ApplyConfigChange(key, func(old, new any) error {
+		fn(old, new)
+		return nil
+	})
+}
+
+// ApplyConfigChange 订阅配置变更, 回调返回错误时本次加载回滚, 已应用的订阅者以原配置再次回调
+func (m *MagicApp) ApplyConfigChange(key string, fn func(old, new any) error) {
+	m.confWatch.mu.Lock()
+	defer m.confWatch.mu.Unlock()
+	m.confWatch.subscribers = append(m.confWatch.subscribers, &configSubscriber{key: key, apply: fn})
+}
+
+// ValidateConfigChange 注册配置变更校验, 校验失败时新配置不生效
+func (m *MagicApp) ValidateConfigChange(key string, fn func(new any) error) {
+	m.confWatch.mu.Lock()
+	defer m.confWatch.mu.Unlock()
+	m.confWatch.subscribers = append(m.confWatch.subscribers, &configSubscriber{key: key, validate: fn})
+}
+
+// adoptConfigWatch 接管其他应用的配置订阅, 排在本应用已有订阅之前
+func (m *MagicApp) adoptConfigWatch(from *MagicApp) {
+	from.confWatch.mu.Lock()
+	pending := from.confWatch.subscribers
+	from.confWatch.subscribers = nil
+	from.confWatch.mu.Unlock()
+
+	m.confWatch.mu.Lock()
+	defer m.confWatch.mu.Unlock()
+	m.confWatch.subscribers = append(pending, m.confWatch.subscribers...)
+}
+
+// OnConfigChange 订阅默认应用的配置变更, 初始化前订阅的在默认应用初始化时生效
+func OnConfigChange(key string, fn func(old, new any)) {
+	Default().OnConfigChange(key, fn)
+}
+
+// ValidateConfigChange 注册默认应用的配置变更校验, 初始化前注册的在默认应用初始化时生效
+func ValidateConfigChange(key string, fn func(new any) error) {
+	Default().ValidateConfigChange(key, fn)
+}
+
+// WatchConfig 以结构体形式订阅配置变更, 新配置解码校验失败时不生效
+func WatchConfig[T any](m *MagicApp, key string, fn func(old, new *T)) {
+	WatchConfigApply(m, key, func(old, new *T) error {
+		fn(old, new)
+		return nil
+	})
+}
+
+// WatchConfigApply 以结构体形式订阅配置变更, 新配置解码校验失败或回调返回错误时不生效
+func WatchConfigApply[T any](m *MagicApp, key string, fn func(old, new *T) error) {
+	m.ValidateConfigChange(key, func(new any) error {
+		_, err := DecodeConfig[T](key, new)
+		return err
+	})
+	m.ApplyConfigChange(key, func(old, new any) error {
+		oldCfg, _ := DecodeConfig[T](key, old)
+		newCfg, _ := DecodeConfig[T](key, new)
+		return fn(oldCfg, newCfg)
+	})
+}
@@ -0,0 +1,300 @@
+package bee
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// 配置绑定使用的结构体标签
+// default: 默认值, 配置中未设置该字段时使用, 切片以逗号分隔
+// validate: 校验规则, 逗号分隔, 支持 required、min=、max=、oneof=a b c、duration
+const (
+	tagDefault  = "default"
+	tagValidate = "validate"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FieldError 配置字段错误
+type FieldError struct {
+	Path string // 完整配置路径, 如dbClient.main.user
+	Rule string // 校验规则
+	Msg  string // 错误说明
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+// ConfigError 配置校验错误, 包含所有不合法的字段
+type ConfigError struct {
+	Key    string       // 配置前缀key
+	Fields []FieldError // 字段错误
+}
+
+func (e *ConfigError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return fmt.Sprintf("<%s>配置校验失败: %s", e.Key, strings.Join(msgs, "; "))
+}
+
+// LoadConfig 从默认应用的配置中加载key对应的配置, 填充默认值并校验
+func LoadConfig[T any](key string) (*T, error) {
+	return LoadConfigFrom[T](Config(), key)
+}
+
+// LoadConfigFrom 从指定配置实例中加载key对应的配置, 填充默认值并校验
+func LoadConfigFrom[T any](conf *viper.Viper, key string) (*T, error) {
+	var raw any
+	if key == "" {
+		raw = conf.AllSettings()
+	} else {
+		raw = conf.Get(key)
+	}
+	return DecodeConfig[T](key, raw)
+}
+
+// DecodeConfig 将原始配置值解码为结构体, 填充默认值并校验, key仅用于错误信息中的路径
+func DecodeConfig[T any](key string, raw any) (*T, error) {
+	cfg := new(T)
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           cfg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if raw != nil {
+		if err = decoder.Decode(raw); err != nil {
+			return nil, fmt.Errorf("<%s>配置转换失败: %w", key, err)
+		}
+	}
+
+	var fields []FieldError
+	walkConfig(reflect.ValueOf(cfg).Elem(), raw, key, &fields)
+	if len(fields) > 0 {
+		return cfg, &ConfigError{Key: key, Fields: fields}
+	}
+
+	return cfg, nil
+}
+
+// walkConfig 递归填充默认值并校验字段, raw为对应的原始配置, 用于判断字段是否已配置
+func walkConfig(v reflect.Value, raw any, path string, errs *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		fieldRaw, present := rawField(raw, name)
+		present = present && fieldRaw != nil
+
+		// 仅未配置的字段使用默认值, 显式配置的零值(0、false、空字符串)保留
+		if def, ok := field.Tag.Lookup(tagDefault); ok && !present && value.IsZero() {
+			if err := setDefault(value, def); err != nil {
+				*errs = append(*errs, FieldError{Path: fieldPath, Rule: tagDefault, Msg: err.Error()})
+				continue
+			}
+		}
+		if rules := field.Tag.Get(tagValidate); rules != "" {
+			for _, rule := range strings.Split(rules, ",") {
+				if msg := checkRule(value, present, strings.TrimSpace(rule)); msg != "" {
+					*errs = append(*errs, FieldError{Path: fieldPath, Rule: rule, Msg: msg})
+				}
+			}
+		}
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			walkConfig(value, fieldRaw, fieldPath, errs)
+		case value.Kind() == reflect.Ptr && value.Type().Elem().Kind() == reflect.Struct && !value.IsNil():
+			walkConfig(value.Elem(), fieldRaw, fieldPath, errs)
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			items := reflect.ValueOf(fieldRaw)
+			for j := 0; j < value.Len(); j++ {
+				var itemRaw any
+				if items.Kind() == reflect.Slice && j < items.Len() {
+					itemRaw = items.Index(j).Interface()
+				}
+				walkConfig(value.Index(j), itemRaw, fmt.Sprintf("%s[%d]", fieldPath, j), errs)
+			}
+		case value.Kind() == reflect.Map && value.Type().Elem().Kind() == reflect.Struct:
+			// map中的结构体不可寻址, 复制后写回; 按key排序保证错误顺序稳定
+			keys := value.MapKeys()
+			sort.Slice(keys, func(a, b int) bool { return fmt.Sprint(keys[a]) < fmt.Sprint(keys[b]) })
+			for _, k := range keys {
+				elem := reflect.New(value.Type().Elem()).Elem()
+				elem.Set(value.MapIndex(k))
+				elemRaw, _ := rawField(fieldRaw, fmt.Sprint(k))
+				walkConfig(elem, elemRaw, fmt.Sprintf("%s.%v", fieldPath, k), errs)
+				value.SetMapIndex(k, elem)
+			}
+		}
+	}
+}
+
+// rawField 在原始配置中查找字段, key与mapstructure一致忽略大小写
+func rawField(raw any, name string) (any, bool) {
+	v := reflect.ValueOf(raw)
+	if v.Kind() != reflect.Map {
+		return nil, false
+	}
+	for _, k := range v.MapKeys() {
+		if strings.EqualFold(fmt.Sprint(k.Interface()), name) {
+			return v.MapIndex(k).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// setDefault 将默认值字符串写入字段
+func setDefault(v reflect.Value, def string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return fmt.Errorf("默认值<%s>不是合法的时长", def)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(def)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(def)
+		if err != nil {
+			return fmt.Errorf("默认值<%s>不是合法的布尔值", def)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(def, 10, 64)
+		if err != nil {
+			return fmt.Errorf("默认值<%s>不是合法的整数", def)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(def, 10, 64)
+		if err != nil {
+			return fmt.Errorf("默认值<%s>不是合法的整数", def)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(def, 64)
+		if err != nil {
+			return fmt.Errorf("默认值<%s>不是合法的数字", def)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("不支持%s类型的默认值", v.Type())
+		}
+		items := strings.Split(def, ",")
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("不支持%s类型的默认值", v.Type())
+	}
+
+	return nil
+}
+
+// checkRule 校验单条规则, present为原始配置中是否配置了该字段, 通过时返回空字符串
+func checkRule(v reflect.Value, present bool, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "required":
+		if v.IsZero() || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0) {
+			return "不能为空"
+		}
+	case "min", "max":
+		if !present && v.IsZero() {
+			// 未配置的字段由required校验, 显式配置的零值仍需校验
+			return ""
+		}
+		return checkRange(v, name, arg)
+	case "oneof":
+		if !present && v.IsZero() {
+			// 未配置的字段不校验, 显式配置的空值需在可选范围内
+			return ""
+		}
+		options := strings.Fields(arg)
+		current := fmt.Sprint(v.Interface())
+		for _, o := range options {
+			if o == current {
+				return ""
+			}
+		}
+		return fmt.Sprintf("取值<%s>不在可选范围[%s]内", current, strings.Join(options, ", "))
+	case "duration":
+		if v.Kind() == reflect.String && v.String() != "" {
+			if _, err := time.ParseDuration(v.String()); err != nil {
+				return fmt.Sprintf("<%s>不是合法的时长, 需带单位如30s、1m30s", v.String())
+			}
+		}
+	default:
+		return fmt.Sprintf("未知的校验规则<%s>", rule)
+	}
+
+	return ""
+}
+
+// checkRange 校验数值/时长/长度范围
+func checkRange(v reflect.Value, name, arg string) string {
+	var current, limit float64
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return fmt.Sprintf("规则<%s=%s>不是合法的时长", name, arg)
+		}
+		current, limit = float64(v.Int()), float64(d)
+	default:
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("规则<%s=%s>不是合法的数字", name, arg)
+		}
+		limit = n
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			current = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			current = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			current = v.Float()
+		case reflect.String, reflect.Slice, reflect.Map:
+			current = float64(v.Len())
+		default:
+			return fmt.Sprintf("%s类型不支持%s规则", v.Type(), name)
+		}
+	}
+
+	if name == "min" && current < limit {
+		return fmt.Sprintf("不能小于%s", arg)
+	}
+	if name == "max" && current > limit {
+		return fmt.Sprintf("不能大于%s", arg)
+	}
+	return ""
+}
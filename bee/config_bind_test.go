@@ -0,0 +1,79 @@
+package bee
+
+import (
+	"testing"
+	"time"
+)
+
+// bindSample 默认值测试配置
+type bindSample struct {
+	Count   int           `mapstructure:"count" default:"5"`
+	Enable  bool          `mapstructure:"enable" default:"true"`
+	Name    string        `mapstructure:"name" default:"demo"`
+	Timeout time.Duration `mapstructure:"timeout" default:"3s"`
+}
+
+func TestDecodeConfigDefaults(t *testing.T) {
+	defaults := bindSample{Count: 5, Enable: true, Name: "demo", Timeout: 3 * time.Second}
+	tests := []struct {
+		name string
+		raw  any
+		want bindSample
+	}{
+		{name: "nil", raw: nil, want: defaults},
+		{name: "absent", raw: map[string]any{}, want: defaults},
+		{name: "null", raw: map[string]any{"count": nil, "enable": nil, "name": nil, "timeout": nil}, want: defaults},
+		{
+			name: "explicit zero",
+			raw:  map[string]any{"count": 0, "enable": false, "name": "", "timeout": "0s"},
+			want: bindSample{},
+		},
+		{
+			name: "explicit zero from env",
+			raw:  map[string]any{"count": "0", "enable": "false", "name": "", "timeout": "0"},
+			want: bindSample{},
+		},
+		{
+			name: "explicit value",
+			raw:  map[string]any{"count": 1, "enable": true, "name": "x", "timeout": "1m"},
+			want: bindSample{Count: 1, Enable: true, Name: "x", Timeout: time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeConfig[bindSample]("sample", tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// ruleSample 校验规则测试配置
+type ruleSample struct {
+	Mode string `mapstructure:"mode" validate:"oneof=a b"`
+	Size int    `mapstructure:"size" default:"10" validate:"min=1"`
+}
+
+func TestDecodeConfigExplicitZeroValidated(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     map[string]any
+		wantErr bool
+	}{
+		{name: "absent", raw: map[string]any{}},
+		{name: "explicit empty oneof", raw: map[string]any{"mode": ""}, wantErr: true},
+		{name: "explicit zero min", raw: map[string]any{"size": 0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeConfig[ruleSample]("sample", tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package bee
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// defaultPollInterval 轮询类配置源的默认间隔
+const defaultPollInterval = 30 * time.Second
+
+// ConfigSource 配置源, 加载结果为嵌套map, 多个配置源按顺序叠加, 后者覆盖前者
+type ConfigSource interface {
+	Name() string                                     // 来源名称, 用于配置来源追踪
+	Load(ctx context.Context) (map[string]any, error) // 加载配置
+}
+
+// WatchableSource 支持变更通知的配置源
+// Watch阻塞至ctx取消, 配置变化时以新配置回调, 加载失败时回调错误
+type WatchableSource interface {
+	ConfigSource
+	Watch(ctx context.Context, onChange func(data map[string]any, err error)) error
+}
+
+// FileSource 配置文件源, 支持yaml、json、toml等viper支持的格式
+type FileSource struct {
+	Path string // 配置文件路径
+}
+
+// NewFileSource 创建配置文件源
+func NewFileSource(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+func (s *FileSource) Name() string { return s.Path }
+
+// Load 读取配置文件
+func (s *FileSource) Load(context.Context) (map[string]any, error) {
+	conf := viper.New()
+	conf.SetConfigFile(s.Path)
+	if err := conf.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return conf.AllSettings(), nil
+}
+
+// Watch 监听配置文件所在目录, 兼容编辑器及k8s ConfigMap的替换写入
+func (s *FileSource) Watch(ctx context.Context, onChange func(map[string]any, error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	path := filepath.Clean(s.Path)
+	if err = watcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+	realPath, _ := filepath.EvalSymlinks(path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			current, _ := filepath.EvalSymlinks(path)
+			changed := filepath.Clean(event.Name) == path && event.Has(fsnotify.Write|fsnotify.Create)
+			if !changed && (current == "" || current == realPath) {
+				continue
+			}
+			realPath = current
+			onChange(s.Load(ctx))
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			onChange(nil, err)
+		}
+	}
+}
+
+// EnvSource 环境变量源, 加载带前缀的环境变量, 去掉前缀后按下划线拆分为配置key
+// 如前缀APP时APP_DBCLIENT_MAIN_HOST对应dbclient.main.host
+type EnvSource struct {
+	Prefix string // 环境变量前缀
+}
+
+// NewEnvSource 创建环境变量源
+func NewEnvSource(prefix string) *EnvSource {
+	return &EnvSource{Prefix: prefix}
+}
+
+func (s *EnvSource) Name() string { return "env:" + strings.ToUpper(s.Prefix) + "_*" }
+
+// Load 读取带前缀的环境变量
+func (s *EnvSource) Load(context.Context) (map[string]any, error) {
+	prefix := strings.ToUpper(s.Prefix) + "_"
+	data := make(map[string]any)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(strings.ToUpper(name), prefix) && len(name) > len(prefix) {
+			setPath(data, strings.Split(strings.ToLower(name[len(prefix):]), "_"), value)
+		}
+	}
+	return data, nil
+}
+
+// HTTPSource HTTP配置源, 请求返回JSON对象, 定时轮询变更
+type HTTPSource struct {
+	URL      string        // 配置地址
+	Header   http.Header   // 请求头, 如鉴权信息
+	Interval time.Duration // 轮询间隔, 默认30s
+	Client   *http.Client  // 请求客户端, 默认超时10s
+}
+
+// NewHTTPSource 创建HTTP配置源
+func NewHTTPSource(url string, interval time.Duration) *HTTPSource {
+	return &HTTPSource{URL: url, Interval: interval}
+}
+
+func (s *HTTPSource) Name() string { return s.URL }
+
+// Load 请求配置地址并解析JSON
+func (s *HTTPSource) Load(ctx context.Context) (map[string]any, error) {
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("<%s>请求配置失败: %s", s.URL, resp.Status)
+	}
+
+	data := make(map[string]any)
+	if err = json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("<%s>配置不是合法的JSON对象: %w", s.URL, err)
+	}
+	return data, nil
+}
+
+// Watch 定时轮询配置变更
+func (s *HTTPSource) Watch(ctx context.Context, onChange func(map[string]any, error)) error {
+	return pollSource(ctx, s, s.Interval, onChange)
+}
+
+// KVStore 键值存储(如etcd、consul), 返回prefix下的所有键值
+type KVStore interface {
+	List(ctx context.Context, prefix string) (map[string]string, error)
+}
+
+// KVSource 键值存储配置源, 键去掉前缀后以/分隔层级, 定时轮询变更
+// 如前缀config/app时config/app/dbClient/main/host对应dbClient.main.host
+type KVSource struct {
+	Store    KVStore       // 键值存储
+	Prefix   string        // 键前缀
+	Interval time.Duration // 轮询间隔, 默认30s
+}
+
+// NewKVSource 创建键值存储配置源
+func NewKVSource(store KVStore, prefix string, interval time.Duration) *KVSource {
+	return &KVSource{Store: store, Prefix: prefix, Interval: interval}
+}
+
+func (s *KVSource) Name() string { return "kv:" + s.Prefix }
+
+// Load 读取前缀下的所有键值
+func (s *KVSource) Load(ctx context.Context) (map[string]any, error) {
+	items, err := s.Store.List(ctx, s.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]any)
+	for key, value := range items {
+		key = strings.Trim(strings.TrimPrefix(key, s.Prefix), "/")
+		if key == "" {
+			continue
+		}
+		setPath(data, strings.Split(key, "/"), value)
+	}
+	return data, nil
+}
+
+// Watch 定时轮询配置变更
+func (s *KVSource) Watch(ctx context.Context, onChange func(map[string]any, error)) error {
+	return pollSource(ctx, s, s.Interval, onChange)
+}
+
+// pollSource 定时加载配置源, 内容变化或加载失败时回调
+// 首次轮询总会回调, 避免遗漏初始加载与开始监听之间的变更, 未变化的配置项不会通知订阅者
+func pollSource(ctx context.Context, src ConfigSource, interval time.Duration, onChange func(map[string]any, error)) error {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	var last map[string]any
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			data, err := src.Load(ctx)
+			if err != nil {
+				onChange(nil, err)
+				continue
+			}
+			if last == nil || !reflect.DeepEqual(data, last) {
+				last = data
+				onChange(data, nil)
+			}
+		}
+	}
+}
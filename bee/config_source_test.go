@@ -0,0 +1,215 @@
+package bee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPSourceLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    map[string]any
+		wantErr bool
+	}{
+		{name: "ok", status: http.StatusOK, body: `{"app":{"name":"demo"}}`, want: map[string]any{"app": map[string]any{"name": "demo"}}},
+		{name: "status", status: http.StatusInternalServerError, body: `{}`, wantErr: true},
+		{name: "invalid json", status: http.StatusOK, body: `[1,2]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-Token") != "abc" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(tt.status)
+				_, _ = fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			src := NewHTTPSource(srv.URL, time.Second)
+			src.Header = http.Header{"X-Token": {"abc"}}
+			got, err := src.Load(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPSourceWatch(t *testing.T) {
+	var version atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"version":%d}`, version.Load())
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan map[string]any, 10)
+	go func() {
+		_ = NewHTTPSource(srv.URL, 20*time.Millisecond).Watch(ctx, func(data map[string]any, err error) {
+			if err == nil {
+				changes <- data
+			}
+		})
+	}()
+
+	// 首次轮询总会通知, 之后仅在内容变化时通知
+	for _, want := range []int64{0, 1} {
+		select {
+		case data := <-changes:
+			if data["version"] != float64(want) {
+				t.Fatalf("version = %v, want %d", data["version"], want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for version %d", want)
+		}
+		version.Add(1)
+	}
+}
+
+// memStore 内存键值存储
+type memStore map[string]string
+
+func (s memStore) List(_ context.Context, prefix string) (map[string]string, error) {
+	if s == nil {
+		return nil, errors.New("unavailable")
+	}
+	return s, nil
+}
+
+func TestKVSourceLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   memStore
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:  "nested keys",
+			store: memStore{"config/app/dbClient/main/host": "db", "config/app/port": "80", "config/app/": "root"},
+			want:  map[string]any{"dbClient": map[string]any{"main": map[string]any{"host": "db"}}, "port": "80"},
+		},
+		{name: "store error", store: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewKVSource(tt.store, "config/app", 0).Load(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvSourceLoad(t *testing.T) {
+	t.Setenv("BEETEST_DBCLIENT_MAIN_HOST", "db")
+	t.Setenv("BEETEST_PORT", "80")
+	got, err := NewEnvSource("beetest").Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"dbclient": map[string]any{"main": map[string]any{"host": "db"}}, "port": "80"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFileSourceWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("port: 80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := NewFileSource(path)
+	data, err := src.Load(context.Background())
+	if err != nil || data["port"] != 80 {
+		t.Fatalf("Load() = %v, %v", data, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan map[string]any, 10)
+	go func() {
+		_ = src.Watch(ctx, func(data map[string]any, err error) {
+			if err == nil {
+				changes <- data
+			}
+		})
+	}()
+	// 等待开始监听
+	time.Sleep(100 * time.Millisecond)
+
+	// 以替换方式写入, 与编辑器及ConfigMap的更新方式一致
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, []byte("port: 81\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case data := <-changes:
+			if data["port"] == 81 {
+				return
+			}
+		case <-deadline:
+			t.Fatal("timeout waiting for file change")
+		}
+	}
+}
+
+func TestConfigSourcesLayering(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"app":{"name":"remote","mode":"http"}}`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	conf := "app:\n  name: file\n  region: cn\n  log:\n    filePath: " + dir + "\n    outputs:\n      - type: file\n"
+	if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app := New(
+		WithConfig(dir, "app"),
+		WithConfigSources(NewHTTPSource(srv.URL, 0), NewKVSource(memStore{"cfg/app/mode": "kv"}, "cfg", 0)),
+	)
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, want, source string
+	}{
+		{"app.name", "remote", srv.URL},
+		{"app.mode", "kv", "kv:cfg"},
+		{"app.region", "cn", filepath.Join(dir, "app.yaml")},
+	}
+	for _, tt := range tests {
+		if got := app.Config().GetString(tt.key); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.key, got, tt.want)
+		}
+		if got := app.ConfigSource(tt.key); got != tt.source {
+			t.Errorf("%s source = %s, want %s", tt.key, got, tt.source)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package bee
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfigSubscriptionsBeforeInit(t *testing.T) {
+	defaultApp.Store(nil)
+	t.Cleanup(func() { defaultApp.Store(nil) })
+
+	// 初始化前通过包级函数订阅, 注册在占位应用上
+	var changed any
+	OnConfigChange("app.name", func(_, new any) { changed = new })
+	ValidateConfigChange("app.name", func(new any) error {
+		if new == "bad" {
+			return errors.New("bad name")
+		}
+		return nil
+	})
+
+	app := New(WithViper(testViper(t, map[string]any{"app.name": "a"})), WithDefault())
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	app.baseConf = testViper(t, map[string]any{"app.name": "bad"})
+	if err := app.ReloadConfig(); err == nil {
+		t.Error("ReloadConfig() = nil, want validation error from subscription registered before Init")
+	}
+	app.baseConf = testViper(t, map[string]any{"app.name": "b"})
+	if err := app.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if changed != "b" {
+		t.Errorf("changed = %v, want b", changed)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package bee
+
+import "math"
+
+// diskFree 当前平台不支持获取磁盘空间, 视为空间充足
+func diskFree(string) (uint64, error) {
+	return math.MaxUint64, nil
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin
+
+package bee
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskFree 目录所在磁盘的可用空间(字节)
+func diskFree(dir string) (uint64, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
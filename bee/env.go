@@ -0,0 +1,141 @@
+package bee
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// defaultEnvKeyReplacer 默认配置key与环境变量名的转换规则
+var defaultEnvKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// loadDotEnv 加载.env文件, 已存在的环境变量不覆盖
+func loadDotEnv(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d 格式错误", path, lineNo)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		if _, exists := os.LookupEnv(key); !exists {
+			if err = os.Setenv(key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// envOverrides 根据环境变量生成覆盖配置
+// 已存在的配置key总会被对应的环境变量覆盖; 设置了前缀时, 带前缀的环境变量按下划线拆分为新的配置key
+// 未设置前缀时只覆盖至少两级的key, 避免user、path、home等顶级key被USER、PATH等系统环境变量覆盖
+func (m *MagicApp) envOverrides(conf *viper.Viper) (map[string]any, map[string]string) {
+	replacer := m.EnvKeyReplacer
+	if replacer == nil {
+		replacer = defaultEnvKeyReplacer
+	}
+	prefix := ""
+	if m.EnvPrefix != "" {
+		prefix = strings.ToUpper(m.EnvPrefix) + "_"
+	}
+
+	known := make(map[string]string)
+	for _, key := range conf.AllKeys() {
+		if prefix == "" && !strings.Contains(key, ".") {
+			continue
+		}
+		known[prefix+strings.ToUpper(replacer.Replace(key))] = key
+	}
+
+	overrides := make(map[string]any)
+	sources := make(map[string]string)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		key, ok := known[strings.ToUpper(name)]
+		if ok {
+			setPath(overrides, strings.Split(key, "."), castLike(conf.Get(key), value))
+			sources[key] = "env:" + name
+			continue
+		}
+		if prefix != "" && strings.HasPrefix(strings.ToUpper(name), prefix) && len(name) > len(prefix) {
+			key = strings.ToLower(name[len(prefix):])
+			setPath(overrides, strings.Split(key, "_"), value)
+			sources[strings.ReplaceAll(key, "_", ".")] = "env:" + name
+		}
+	}
+
+	return overrides, sources
+}
+
+// applyEnv 将环境变量覆盖合并到配置, 返回被覆盖的key及对应的环境变量
+func (m *MagicApp) applyEnv(conf *viper.Viper) (map[string]string, error) {
+	if !m.EnvOverride && m.EnvPrefix == "" {
+		return nil, nil
+	}
+	overrides, sources := m.envOverrides(conf)
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	return sources, conf.MergeConfigMap(overrides)
+}
+
+// setPath 按路径写入嵌套map
+func setPath(root map[string]any, path []string, value any) {
+	for _, p := range path[:len(path)-1] {
+		next, ok := root[p].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			root[p] = next
+		}
+		root = next
+	}
+	root[path[len(path)-1]] = value
+}
+
+// castLike 按原配置值的类型转换环境变量值
+func castLike(origin any, value string) any {
+	var (
+		v   any
+		err error
+	)
+	switch origin.(type) {
+	case bool:
+		v, err = cast.ToBoolE(value)
+	case int:
+		v, err = cast.ToIntE(value)
+	case int64:
+		v, err = cast.ToInt64E(value)
+	case float64:
+		v, err = cast.ToFloat64E(value)
+	case []any, []string:
+		v, err = strings.Split(value, ","), nil
+	default:
+		return value
+	}
+	if err != nil {
+		return value
+	}
+	return v
+}
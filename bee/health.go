@@ -0,0 +1,179 @@
+package bee
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCheckTimeout 默认检查超时时间
+const defaultCheckTimeout = 2 * time.Second
+
+// defaultMinDiskFree 日志目录默认最小剩余空间(M)
+const defaultMinDiskFree = 100
+
+// 检查状态
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// HealthCheck 健康检查项
+type HealthCheck struct {
+	Name     string                          // 检查项名称
+	Check    func(ctx context.Context) error // 检查函数
+	Timeout  time.Duration                   // 超时时间, 默认2s
+	CacheTTL time.Duration                   // 结果缓存时间, 为0时不缓存
+	Liveness bool                            // 是否参与存活检查, 默认只参与就绪检查
+
+	mu     sync.Mutex
+	last   CheckResult
+	lastAt time.Time
+}
+
+// CheckResult 检查结果
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Cost   string `json:"cost"`
+	Cached bool   `json:"cached"`
+}
+
+// HealthReport 检查报告
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// run 执行检查, 缓存有效期内直接返回上次结果
+func (h *HealthCheck) run(ctx context.Context) CheckResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.CacheTTL > 0 && !h.lastAt.IsZero() && time.Since(h.lastAt) < h.CacheTTL {
+		cached := h.last
+		cached.Cached = true
+		return cached
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	begin := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- fmt.Errorf("%v", r)
+			}
+		}()
+		errCh <- h.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = fmt.Errorf("检查超时(%s)", timeout)
+	}
+
+	result := CheckResult{Name: h.Name, Status: StatusUp, Cost: time.Since(begin).String()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	h.last, h.lastAt = result, time.Now()
+
+	return result
+}
+
+// AddHealthCheck 注册健康检查项
+func (m *MagicApp) AddHealthCheck(checks ...*HealthCheck) {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	for _, h := range checks {
+		if h != nil && h.Check != nil {
+			m.healthChecks = append(m.healthChecks, h)
+		}
+	}
+}
+
+// CheckHealth 并发执行检查项, liveness为true时只执行存活检查项
+func (m *MagicApp) CheckHealth(ctx context.Context, liveness bool) HealthReport {
+	m.healthMu.Lock()
+	checks := make([]*HealthCheck, 0, len(m.healthChecks))
+	for _, h := range m.healthChecks {
+		if !liveness || h.Liveness {
+			checks = append(checks, h)
+		}
+	}
+	m.healthMu.Unlock()
+
+	report := HealthReport{Status: StatusUp, Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, h := range checks {
+		wg.Add(1)
+		go func(i int, h *HealthCheck) {
+			defer wg.Done()
+			report.Checks[i] = h.run(ctx)
+		}(i, h)
+	}
+	wg.Wait()
+	for _, r := range report.Checks {
+		if r.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+// healthHandler 存活探针
+func (m *MagicApp) healthHandler(c *gin.Context) {
+	report := m.CheckHealth(c.Request.Context(), true)
+	healthResponse(c, report, "服务异常")
+}
+
+// readyHandler 就绪探针, 关闭排空期间直接返回未就绪
+func (m *MagicApp) readyHandler(c *gin.Context) {
+	if !m.Ready() {
+		healthResponse(c, HealthReport{Status: StatusDown, Checks: []CheckResult{}}, "服务未就绪")
+		return
+	}
+	report := m.CheckHealth(c.Request.Context(), false)
+	healthResponse(c, report, "服务未就绪")
+}
+
+// healthResponse 检查报告响应, 异常时返回503
+func healthResponse(c *gin.Context, report HealthReport, downMsg string) {
+	if report.Status == StatusUp {
+		c.JSON(http.StatusOK, result{OK, GetCodeMsg(OK), report})
+		return
+	}
+	c.JSON(http.StatusServiceUnavailable, result{SystemErr, downMsg, report})
+}
+
+// diskCheck 日志目录磁盘剩余空间检查
+func diskCheck(dir string, minFreeMB uint64) *HealthCheck {
+	return &HealthCheck{
+		Name:     "disk",
+		CacheTTL: 10 * time.Second,
+		Check: func(ctx context.Context) error {
+			free, err := diskFree(dir)
+			if err != nil {
+				return err
+			}
+			if free < minFreeMB<<20 {
+				return fmt.Errorf("日志目录<%s>剩余空间不足: %dM", dir, free>>20)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,205 @@
+package bee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Component 生命周期组件(数据库连接池、Badger、定时任务、消费者等)
+type Component struct {
+	Name      string                          // 组件名称, 唯一
+	DependsOn []string                        // 依赖的组件名称, 依赖项先启动、后停止
+	Start     func(ctx context.Context) error // 启动钩子, 可为空
+	Stop      func(ctx context.Context) error // 停止钩子, 可为空
+}
+
+// ComponentReport 组件执行报告
+type ComponentReport struct {
+	Name   string        // 组件名称
+	Action string        // 动作 start/stop
+	Cost   time.Duration // 耗时
+	Err    error         // 错误
+}
+
+// lifecycle 组件生命周期管理
+type lifecycle struct {
+	mu         sync.Mutex
+	components []*Component      // 注册顺序
+	started    []*Component      // 已启动组件, 按启动顺序
+	reports    []ComponentReport // 执行报告
+	running    bool              // 是否已启动
+}
+
+// register 注册组件, 已启动时检查依赖是否已启动并立即启动新注册的组件, 启动失败时不注册
+func (l *lifecycle) register(cs ...*Component) error {
+	for _, c := range cs {
+		if c == nil {
+			continue
+		}
+		l.mu.Lock()
+		running := l.running
+		// 未启动时重复及依赖由启动时统一检查
+		if running {
+			if slices.ContainsFunc(l.components, func(e *Component) bool { return e.Name == c.Name }) {
+				l.mu.Unlock()
+				return fmt.Errorf("组件<%s>重复注册", c.Name)
+			}
+			for _, dep := range c.DependsOn {
+				if !l.isStarted(dep) {
+					l.mu.Unlock()
+					return fmt.Errorf("组件<%s>依赖的组件<%s>未启动", c.Name, dep)
+				}
+			}
+		}
+		l.components = append(l.components, c)
+		l.mu.Unlock()
+		if !running {
+			continue
+		}
+
+		if err := l.run(context.Background(), c, "start", c.Start); err != nil {
+			l.mu.Lock()
+			l.components = slices.DeleteFunc(l.components, func(e *Component) bool { return e == c })
+			l.mu.Unlock()
+			return fmt.Errorf("组件<%s>启动失败: %w", c.Name, err)
+		}
+		l.mu.Lock()
+		l.started = append(l.started, c)
+		l.mu.Unlock()
+	}
+
+	return nil
+}
+
+// isStarted 组件是否已启动, 需持有锁
+func (l *lifecycle) isStarted(name string) bool {
+	return slices.ContainsFunc(l.started, func(c *Component) bool { return c.Name == name })
+}
+
+// sorted 按依赖关系排序, 同级保持注册顺序
+func (l *lifecycle) sorted() ([]*Component, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	index := make(map[string]*Component, len(l.components))
+	for _, c := range l.components {
+		if _, ok := index[c.Name]; ok {
+			return nil, fmt.Errorf("组件<%s>重复注册", c.Name)
+		}
+		index[c.Name] = c
+	}
+	for _, c := range l.components {
+		for _, dep := range c.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("组件<%s>依赖的组件<%s>未注册", c.Name, dep)
+			}
+		}
+	}
+
+	result := make([]*Component, 0, len(l.components))
+	done := make(map[string]bool, len(l.components))
+	for len(result) < len(l.components) {
+		progress := false
+		for _, c := range l.components {
+			if done[c.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range c.DependsOn {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[c.Name] = true
+				result = append(result, c)
+				progress = true
+			}
+		}
+		if !progress {
+			return nil, errors.New("组件之间存在循环依赖")
+		}
+	}
+
+	return result, nil
+}
+
+// start 按依赖顺序启动组件, 失败时逆序停止已启动的组件
+func (l *lifecycle) start(ctx context.Context) error {
+	components, err := l.sorted()
+	if err != nil {
+		return err
+	}
+	for _, c := range components {
+		if err = l.run(ctx, c, "start", c.Start); err != nil {
+			_ = l.stop(ctx)
+			return fmt.Errorf("组件<%s>启动失败: %w", c.Name, err)
+		}
+		l.mu.Lock()
+		l.started = append(l.started, c)
+		l.mu.Unlock()
+	}
+	l.mu.Lock()
+	l.running = true
+	l.mu.Unlock()
+
+	return nil
+}
+
+// stop 逆序停止已启动的组件
+func (l *lifecycle) stop(ctx context.Context) error {
+	l.mu.Lock()
+	started := l.started
+	l.started = nil
+	l.running = false
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		if err := l.run(ctx, c, "stop", c.Stop); err != nil {
+			errs = append(errs, fmt.Errorf("组件<%s>停止失败: %w", c.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// run 执行钩子并记录报告
+func (l *lifecycle) run(ctx context.Context, c *Component, action string, hook func(ctx context.Context) error) error {
+	var err error
+	begin := time.Now()
+	if hook != nil {
+		err = hook(ctx)
+	}
+	report := ComponentReport{Name: c.Name, Action: action, Cost: time.Since(begin), Err: err}
+	l.mu.Lock()
+	l.reports = append(l.reports, report)
+	l.mu.Unlock()
+
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+	fmt.Printf("[%s] 组件<%s> %s...%s (%s)\n", time.Now().Format(time.DateTime), c.Name, action, status, report.Cost)
+
+	return err
+}
+
+// Register 注册生命周期组件, 应用已启动时立即启动, 返回依赖未启动或启动失败的错误
+func (m *MagicApp) Register(cs ...*Component) error {
+	return m.lifecycle.register(cs...)
+}
+
+// Reports 获取组件执行报告
+func (m *MagicApp) Reports() []ComponentReport {
+	m.lifecycle.mu.Lock()
+	defer m.lifecycle.mu.Unlock()
+
+	return append([]ComponentReport(nil), m.lifecycle.reports...)
+}
@@ -0,0 +1,243 @@
+package bee
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 监听名称, systemd socket激活时通过FileDescriptorName区分
+const (
+	PublicListener = "public" // 业务路由
+	AdminListener  = "admin"  // 管理路由
+)
+
+// server 服务及其监听
+type server struct {
+	name  string
+	srv   *http.Server
+	lns   []net.Listener
+	tls   bool
+	drain *drainState // 不为空时跟踪被劫持的连接
+}
+
+// serve 在所有监听上提供服务
+func (s *server) serve(errCh chan<- error) {
+	for _, ln := range s.lns {
+		go func(ln net.Listener) {
+			if s.drain != nil {
+				ln = &trackedListener{Listener: ln, drain: s.drain}
+			}
+			var err error
+			if s.tls {
+				err = s.srv.ServeTLS(ln, "", "")
+			} else {
+				err = s.srv.Serve(ln)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				select {
+				case errCh <- fmt.Errorf("<%s>%s监听错误: %w", s.name, ln.Addr(), err):
+				default:
+				}
+			}
+		}(ln)
+	}
+}
+
+// systemdListeners 获取systemd socket激活传入的监听, 按名称分组
+func systemdListeners() (map[string][]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	return fileListeners(3, n, names)
+}
+
+// fileListeners 将继承的文件描述符转换为监听, 未命名的归入public, 失败时关闭所有监听及剩余的文件描述符
+func fileListeners(start, n int, names []string) (map[string][]net.Listener, error) {
+	result := make(map[string][]net.Listener)
+	for i := 0; i < n; i++ {
+		name := PublicListener
+		if i < len(names) && names[i] == AdminListener {
+			name = AdminListener
+		}
+		f := os.NewFile(uintptr(start+i), name)
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, lns := range result {
+				for _, ln := range lns {
+					_ = ln.Close()
+				}
+			}
+			for j := i + 1; j < n; j++ {
+				_ = os.NewFile(uintptr(start+j), "").Close()
+			}
+			return nil, fmt.Errorf("继承的文件描述符<%d>无法监听: %w", start+i, err)
+		}
+		result[name] = append(result[name], ln)
+	}
+
+	return result, nil
+}
+
+// listen 创建所有监听, 优先使用平滑重启或systemd传入的监听
+func (m *MagicApp) listen() (public, admin []net.Listener, err error) {
+	inherited, err := inheritedListeners()
+	if err == nil && inherited == nil {
+		inherited, err = systemdListeners()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	public, admin = inherited[PublicListener], inherited[AdminListener]
+	defer func() {
+		if err != nil {
+			for _, ln := range append(public, admin...) {
+				_ = ln.Close()
+			}
+		}
+	}()
+
+	if len(public) == 0 {
+		if m.Addr != "" || m.UnixSocket == "" {
+			addr := m.Addr
+			if addr == "" {
+				addr = ":http"
+			}
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return public, admin, err
+			}
+			public = append(public, ln)
+		}
+		if m.UnixSocket != "" {
+			if err = removeStaleSocket(m.UnixSocket); err != nil {
+				return public, admin, err
+			}
+			ln, err := net.Listen("unix", m.UnixSocket)
+			if err != nil {
+				return public, admin, err
+			}
+			public = append(public, ln)
+		}
+	}
+	if len(admin) == 0 && m.AdminAddr != "" {
+		ln, err := net.Listen("tcp", adminListenAddr(m.AdminAddr))
+		if err != nil {
+			return public, admin, err
+		}
+		admin = append(admin, ln)
+	}
+
+	return public, admin, nil
+}
+
+// removeStaleSocket 删除上次运行遗留的Unix套接字文件, 路径存在但不是套接字时返回错误
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("<%s>已存在且不是Unix套接字", path)
+	}
+	return os.Remove(path)
+}
+
+// adminListenAddr 管理地址未指定主机时(如:9090)仅监听本机回环地址
+func adminListenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// servers 创建公共服务和管理服务
+func (m *MagicApp) servers() ([]*server, error) {
+	public, admin, err := m.listen()
+	if err != nil {
+		return nil, err
+	}
+	servers := []*server{{
+		name:  PublicListener,
+		srv:   &http.Server{Handler: m.Router, ConnState: m.trackConnState, TLSConfig: m.tlsConfig},
+		lns:   public,
+		tls:   m.tlsConfig != nil,
+		drain: &m.drain,
+	}}
+	if len(admin) > 0 {
+		servers = append(servers, &server{
+			name: AdminListener,
+			srv:  &http.Server{Handler: m.AdminRouter},
+			lns:  admin,
+		})
+	}
+	for _, s := range servers {
+		for _, ln := range s.lns {
+			fmt.Printf("[%s] <%s>监听地址: %s://%s\n", time.Now().Format(time.DateTime), s.name, ln.Addr().Network(), ln.Addr())
+		}
+	}
+
+	return servers, nil
+}
+
+// initAdminRouter 初始化管理路由: 心跳、存活探针、就绪探针、指标、日志级别、pprof
+// 管理服务使用HTTP, 日志级别及pprof需携带管理令牌
+func (m *MagicApp) initAdminRouter() {
+	if m.AdminRouter == nil {
+		m.AdminRouter = gin.New()
+		m.AdminRouter.Use(gin.Recovery())
+	}
+	m.AdminRouter.Use(m.bindApp())
+	m.AdminRouter.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "success! This service is normal.")
+	})
+	m.AdminRouter.GET("/healthz", m.healthHandler)
+	m.AdminRouter.GET("/readyz", m.readyHandler)
+
+	m.AdminRouter.GET("/metrics", m.metricsHandler)
+
+	// 日志级别查询及调整, 需携带管理令牌
+	lg := m.AdminRouter.Group("/log", m.adminAuth())
+	lg.GET("/level", m.getLogLevel)
+	lg.PUT("/level", m.putLogLevel)
+
+	g := m.AdminRouter.Group("/debug/pprof", m.adminAuth())
+	g.GET("/", gin.WrapF(pprof.Index))
+	g.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	g.GET("/profile", gin.WrapF(pprof.Profile))
+	g.GET("/symbol", gin.WrapF(pprof.Symbol))
+	g.POST("/symbol", gin.WrapF(pprof.Symbol))
+	g.GET("/trace", gin.WrapF(pprof.Trace))
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		g.GET("/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+
+	if m.RegAdminRouteFun != nil {
+		m.RegAdminRouteFun(m.AdminRouter)
+	}
+}
@@ -1,72 +1,153 @@
 package bee
 
 import (
-	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"reflect"
 	"time"
 
-	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// Logger 默认应用的日志, 初始化时设置, 日志重建后仍写入当前日志
 var Logger *zap.SugaredLogger
 
+// retireGrace 日志重建后旧输出的保留时间, 期间已获取的旧日志仍可写入, 之后关闭
+const retireGrace = 5 * time.Second
+
 // LogCfg 日志配置
 type logConfig struct {
-	FilePath      string `mapstructure:"filePath"`      // 日志文件路径
-	FileName      string `mapstructure:"fileName"`      // 日志文件名
-	MaxSize       int    `mapstructure:"maxSize"`       // 单文件最大体积(M),超过后自动切分
-	MaxBackups    int    `mapstructure:"maxBackups"`    // 保留历史文件的最大个数
-	MaxAge        int    `mapstructure:"maxAge"`        // 保留天数
-	IsJsonEncoder bool   `mapstructure:"isJsonEncoder"` // 是否使用JSON编码器
+	FilePath      string            `mapstructure:"filePath" default:"log"`                                                         // 日志文件路径
+	FileName      string            `mapstructure:"fileName" default:"app"`                                                         // 日志文件名
+	MaxSize       int               `mapstructure:"maxSize" default:"2" validate:"min=1"`                                           // 单文件最大体积(M),超过后自动切分
+	MaxBackups    int               `mapstructure:"maxBackups" default:"10" validate:"min=-1"`                                      // 保留历史文件的最大个数, -1表示不限制
+	MaxAge        int               `mapstructure:"maxAge" default:"7" validate:"min=-1"`                                           // 保留天数, -1表示不限制
+	Rotation      string            `mapstructure:"rotation" default:"daily" validate:"oneof=daily hourly none"`                    // 按时间滚动周期 daily/hourly/none
+	Compress      bool              `mapstructure:"compress"`                                                                       // 是否gzip压缩滚动出的文件
+	Outputs       []logOutput       `mapstructure:"outputs"`                                                                        // 日志输出列表, 为空时输出到日志文件及标准输出
+	IsJsonEncoder bool              `mapstructure:"isJsonEncoder"`                                                                  // 是否使用JSON编码器
+	Level         string            `mapstructure:"level" default:"info" validate:"oneof=debug info warn error dpanic panic fatal"` // 日志级别
+	Async         logAsync          `mapstructure:"async"`                                                                          // 异步写入
+	Sampling      logSampling       `mapstructure:"sampling"`                                                                       // 日志采样
+	Modules       map[string]string `mapstructure:"modules"`                                                                        // 模块日志级别, key为日志名称(Logger().Named), 如db: debug
+}
+
+// MagicLog 日志
+type MagicLog struct {
+	cfg     *logConfig
+	conf    *viper.Viper
+	levels  *moduleLevels       // 全局及模块日志级别, 可运行时调整
+	closers []io.Closer         // 需关闭的输出(日志文件、syslog、网络连接)
+	logger  *zap.SugaredLogger  // 当前日志
+	LogKey  string              // 日志配置前缀key
+	OnDrop  func(reason string) // 丢弃日志时的回调, 用于按原因计数
+}
+
+// 日志丢弃原因
+const (
+	DropSampling = "sampling" // 采样丢弃
+	DropAsync    = "async"    // 异步队列溢出丢弃
+	DropRemote   = "remote"   // 远程输出队列满或落盘超限丢弃
+)
+
+// dropHook 指定原因的丢弃回调
+func (m *MagicLog) dropHook(reason string) func() {
+	if m.OnDrop == nil {
+		return func() {}
+	}
+	return func() { m.OnDrop(reason) }
 }
 
-// setDefault 设置默认值
-func (l *logConfig) setDefault() {
-	if l.FilePath == "" {
-		l.FilePath = "log"
+func (m *MagicLog) initLogger() (*zap.SugaredLogger, error) {
+	// 配置
+	config, err := LoadConfigFrom[logConfig](m.conf, m.LogKey)
+	if err != nil {
+		return nil, err
 	}
-	if l.FileName == "" {
-		l.FileName = "app"
+	m.levels = &moduleLevels{global: zap.NewAtomicLevel()}
+
+	return m.build(config)
+}
+
+// build 按配置创建日志, 失败时保留原配置及级别
+func (m *MagicLog) build(config *logConfig) (*zap.SugaredLogger, error) {
+	if _, err := zapcore.ParseLevel(config.Level); err != nil {
+		return nil, err
 	}
-	if l.MaxSize == 0 {
-		l.MaxSize = 2
+	if _, err := parseModuleLevels(config.Modules); err != nil {
+		return nil, err
 	}
-	if l.MaxBackups == 0 {
-		l.MaxBackups = 10
+	prev := m.cfg
+	m.cfg = config
+
+	// 创建各输出的日志核心, 全局及模块级别由levelCore按日志名称判断
+	cores, closers, err := m.buildCores()
+	if err != nil {
+		m.cfg = prev
+		closeAll(closers)
+		return nil, err
 	}
-	if l.MaxAge == 0 {
-		l.MaxAge = 7
+	_ = m.applyLevels(config)
+	m.closers = closers
+	core := zapcore.NewTee(cores...)
+	if config.Async.Enable {
+		async := newAsyncCore(core, config.Async, m.dropHook(DropAsync))
+		// 先停止队列写完日志, 再关闭各输出
+		m.closers = append([]io.Closer{async.queue}, m.closers...)
+		core = async
 	}
+	core = &levelCore{Core: newSamplingCore(core, config.Sampling, m.dropHook(DropSampling)), levels: m.levels}
+	m.logger = zap.New(core, zap.AddStacktrace(zapcore.ErrorLevel)).Sugar()
+	return m.logger, nil
 }
 
-// MagicLog 日志
-type MagicLog struct {
-	cfg    *logConfig
-	LogKey string // 日志配置前缀key
+// applyLevels 应用全局及模块日志级别
+func (m *MagicLog) applyLevels(config *logConfig) error {
+	level, err := zapcore.ParseLevel(config.Level)
+	if err != nil {
+		return err
+	}
+	modules, err := parseModuleLevels(config.Modules)
+	if err != nil {
+		return err
+	}
+	m.levels.set("", level)
+	m.levels.reset(modules)
+	return nil
 }
 
-func (m *MagicLog) initLogger() {
-	// 配置
-	var config logConfig
-	if err := mapstructure.Decode(viper.GetStringMap(m.LogKey), &config); err != nil {
-		panic(errors.New("日志配置转换失败。"))
+// reconfigure 日志配置变更, 仅级别变化时直接调整级别返回nil, 否则重建日志
+func (m *MagicLog) reconfigure(config *logConfig) (*zap.SugaredLogger, error) {
+	onlyLevel := *m.cfg
+	onlyLevel.Level, onlyLevel.Modules = config.Level, config.Modules
+	if reflect.DeepEqual(onlyLevel, *config) {
+		if err := m.applyLevels(config); err != nil {
+			return nil, err
+		}
+		m.cfg = config
+		return nil, nil
+	}
+
+	oldLogger, oldClosers := m.logger, m.closers
+	logger, err := m.build(config)
+	if err != nil {
+		return nil, err
 	}
-	config.setDefault()
-	m.cfg = &config
+	go retire(oldLogger, oldClosers)
+	return logger, nil
+}
 
-	// 创建核心日志组件
-	core := zapcore.NewCore(m.getEncoder(), zapcore.NewMultiWriteSyncer(m.getWriteSyncer(), zapcore.AddSync(os.Stdout)), zapcore.InfoLevel)
-	Logger = zap.New(core, zap.AddStacktrace(zapcore.ErrorLevel)).Sugar()
+// retire 刷新旧日志, 保留retireGrace后关闭旧输出
+func retire(logger *zap.SugaredLogger, closers []io.Closer) {
+	_ = logger.Sync()
+	time.Sleep(retireGrace)
+	closeAll(closers)
 }
 
 // getEncoder 获取编码器
-func (m *MagicLog) getEncoder() zapcore.Encoder {
+func (m *MagicLog) getEncoder(isJson bool) zapcore.Encoder {
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.LevelKey = "L"
 	encoderConfig.TimeKey = "T"
@@ -76,26 +157,16 @@ func (m *MagicLog) getEncoder() zapcore.Encoder {
 	encoderConfig.EncodeTime = func(time time.Time, encoder zapcore.PrimitiveArrayEncoder) {
 		encoder.AppendString(fmt.Sprintf("[%s]", time.Local().Format("2006/01/02 15:04:05.00000")))
 	}
-	if m.cfg.IsJsonEncoder {
+	if isJson {
 		return zapcore.NewJSONEncoder(encoderConfig)
 	}
 
 	return zapcore.NewConsoleEncoder(encoderConfig)
 }
 
-// getWriteSyncer 写入日志配置
-func (m *MagicLog) getWriteSyncer() zapcore.WriteSyncer {
-	// 设置日志存放目录及名称
-	stSeparator := string(filepath.Separator)
-	logFile := fmt.Sprintf("%s%s%s_%s.log", m.cfg.FilePath, stSeparator, m.cfg.FileName, time.Now().Format(time.DateOnly))
-	// 设置文件切割,需用到第三方包(lumberjack),zap本身不支持
-	lumberjackSyncer := &lumberjack.Logger{
-		Filename:   logFile,
-		MaxSize:    m.cfg.MaxSize,
-		MaxBackups: m.cfg.MaxBackups,
-		MaxAge:     m.cfg.MaxAge,
-		Compress:   false,
+// closeAll 关闭输出
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		_ = c.Close()
 	}
-
-	return zapcore.AddSync(lumberjackSyncer)
 }
@@ -0,0 +1,211 @@
+package bee
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 异步日志队列满时的处理策略
+const (
+	OverflowBlock      = "block"      // 阻塞等待
+	OverflowDropOldest = "dropOldest" // 丢弃最早的日志
+	OverflowDropDebug  = "dropDebug"  // 丢弃debug日志, 其他级别阻塞等待
+)
+
+// logAsync 异步日志配置
+type logAsync struct {
+	Enable        bool          `mapstructure:"enable"`                                                               // 是否异步写入
+	QueueSize     int           `mapstructure:"queueSize" default:"8192" validate:"min=1"`                            // 队列长度
+	Overflow      string        `mapstructure:"overflow" default:"block" validate:"oneof=block dropOldest dropDebug"` // 队列满时的策略 block/dropOldest/dropDebug
+	FlushInterval time.Duration `mapstructure:"flushInterval" default:"1s" validate:"min=10ms"`                       // 定时刷新间隔
+	BufferSize    int           `mapstructure:"bufferSize" default:"262144" validate:"min=0"`                         // 日志文件写缓冲字节数, 0表示不缓冲
+}
+
+// asyncEntry 队列中的日志
+type asyncEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// asyncQueue 异步日志队列, 由单个协程按顺序写入
+// 刷新请求不经过日志队列, 避免dropOldest丢弃刷新请求
+type asyncQueue struct {
+	root     zapcore.Core // 用于刷新
+	ch       chan asyncEntry
+	flushCh  chan chan struct{}
+	overflow string
+	onDrop   func()
+	mu       sync.RWMutex
+	closed   bool
+	exited   chan struct{}
+}
+
+// run 写入日志并定时刷新
+func (q *asyncQueue) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-q.ch:
+			if !ok {
+				_ = q.root.Sync()
+				close(q.exited)
+				return
+			}
+			writeEntry(e.core, e.ent, e.fields)
+		case done := <-q.flushCh:
+			q.drain()
+			_ = q.root.Sync()
+			close(done)
+		case <-ticker.C:
+			_ = q.root.Sync()
+		}
+	}
+}
+
+// drain 写入刷新请求之前入队的日志, 不等待之后入队的日志
+func (q *asyncQueue) drain() {
+	for n := len(q.ch); n > 0; n-- {
+		select {
+		case e, ok := <-q.ch:
+			if !ok {
+				return
+			}
+			writeEntry(e.core, e.ent, e.fields)
+		default:
+			// 其余日志已被dropOldest丢弃
+			return
+		}
+	}
+}
+
+// enqueue 按溢出策略入队, 队列已关闭时返回false
+func (q *asyncQueue) enqueue(e asyncEntry) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+
+	switch {
+	case q.overflow == OverflowDropOldest:
+		for {
+			select {
+			case q.ch <- e:
+				return true
+			default:
+			}
+			select {
+			case <-q.ch:
+				q.onDrop()
+			default:
+			}
+		}
+	case q.overflow == OverflowDropDebug && e.ent.Level <= zapcore.DebugLevel:
+		select {
+		case q.ch <- e:
+		default:
+			q.onDrop()
+		}
+	default:
+		q.ch <- e
+	}
+	return true
+}
+
+// flush 等待队列中的日志全部写入并刷新
+func (q *asyncQueue) flush() error {
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		return q.root.Sync()
+	}
+	done := make(chan struct{})
+	q.flushCh <- done
+	q.mu.RUnlock()
+	<-done
+	return nil
+}
+
+// Close 写完队列中的日志后停止, 之后的日志同步写入
+func (q *asyncQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.ch)
+	q.mu.Unlock()
+	<-q.exited
+	return nil
+}
+
+// asyncCore 异步日志核心, 写入请求放入队列后立即返回
+type asyncCore struct {
+	zapcore.Core
+	queue *asyncQueue
+}
+
+// newAsyncCore 创建异步日志核心及其队列
+func newAsyncCore(core zapcore.Core, cfg logAsync, onDrop func()) *asyncCore {
+	q := &asyncQueue{
+		root:     core,
+		ch:       make(chan asyncEntry, cfg.QueueSize),
+		flushCh:  make(chan chan struct{}),
+		overflow: cfg.Overflow,
+		onDrop:   onDrop,
+		exited:   make(chan struct{}),
+	}
+	go q.run(cfg.FlushInterval)
+	return &asyncCore{Core: core, queue: q}
+}
+
+func (c *asyncCore) With(fields []zapcore.Field) zapcore.Core {
+	return &asyncCore{Core: c.Core.With(fields), queue: c.queue}
+}
+
+func (c *asyncCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 入队, dpanic及以上级别先写完队列再同步写入, 避免进程退出丢失日志
+func (c *asyncCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level > zapcore.ErrorLevel {
+		_ = c.queue.flush()
+		writeEntry(c.Core, ent, fields)
+		return c.Core.Sync()
+	}
+	e := asyncEntry{core: c.Core, ent: ent, fields: append([]zapcore.Field(nil), fields...)}
+	if !c.queue.enqueue(e) {
+		writeEntry(c.Core, ent, fields)
+	}
+	return nil
+}
+
+// Sync 写完队列中的日志并刷新
+func (c *asyncCore) Sync() error {
+	return c.queue.flush()
+}
+
+// writeEntry 由内部核心按各输出级别写入
+func writeEntry(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) {
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// bufferedCloser 停止文件写缓冲, 停止前刷新
+type bufferedCloser struct {
+	ws *zapcore.BufferedWriteSyncer
+}
+
+func (b bufferedCloser) Close() error {
+	return b.ws.Stop()
+}
@@ -0,0 +1,48 @@
+package bee
+
+import (
+	"testing"
+)
+
+func TestAsyncBufferSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		async    map[string]any
+		wantSize int
+	}{
+		{name: "absent", async: map[string]any{"enable": true}, wantSize: 262144},
+		{name: "zero disables buffering", async: map[string]any{"enable": true, "bufferSize": 0}, wantSize: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]any{
+				"filePath": t.TempDir(),
+				"outputs":  []map[string]any{{"type": OutputFile}},
+				"async":    tt.async,
+			}
+			cfg, err := DecodeConfig[logConfig]("app.log", raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Async.BufferSize != tt.wantSize {
+				t.Fatalf("bufferSize = %d, want %d", cfg.Async.BufferSize, tt.wantSize)
+			}
+
+			m := &MagicLog{cfg: cfg}
+			_, closers, err := m.buildCores()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeAll(closers)
+			buffered := false
+			for _, c := range closers {
+				if _, ok := c.(bufferedCloser); ok {
+					buffered = true
+				}
+			}
+			if buffered != (tt.wantSize > 0) {
+				t.Errorf("file output buffered = %v, want %v", buffered, tt.wantSize > 0)
+			}
+		})
+	}
+}
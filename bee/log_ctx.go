@@ -0,0 +1,161 @@
+package bee
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 请求上下文中的日志字段key, 鉴权中间件通过c.Set(UserIDKey, id)写入用户及租户
+const (
+	TraceIDKey  = "trace_id"   // 链路ID
+	UserIDKey   = "user_id"    // 用户ID
+	TenantKey   = "tenant_id"  // 租户ID
+	TraceHeader = "X-Trace-Id" // 链路ID请求/响应头
+)
+
+// requestInfoKey context.Context中请求信息的key
+type requestInfoKey struct{}
+
+// requestInfo 随context.Context传递的请求信息
+type requestInfo struct {
+	app      *MagicApp
+	traceID  string
+	route    string
+	clientIP string
+	user     *requestUser
+}
+
+// requestUser 用户及租户, 绑定gin上下文时在请求处理期间按需读取, 以获取鉴权中间件之后写入的值
+type requestUser struct {
+	mu     sync.RWMutex
+	c      *gin.Context // 请求结束后置空, 避免读取复用的gin上下文
+	userID string
+	tenant string
+}
+
+// get 获取用户及租户
+func (u *requestUser) get() (string, string) {
+	if u == nil {
+		return "", ""
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if u.c != nil {
+		return u.c.GetString(UserIDKey), u.c.GetString(TenantKey)
+	}
+	return u.userID, u.tenant
+}
+
+// release 固定当前的用户及租户并解除与gin上下文的绑定
+func (u *requestUser) release() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.c != nil {
+		u.userID, u.tenant = u.c.GetString(UserIDKey), u.c.GetString(TenantKey)
+		u.c = nil
+	}
+}
+
+// fields 日志字段, 忽略空值
+func (r *requestInfo) fields() []any {
+	userID, tenant := r.user.get()
+	fields := make([]any, 0, 10)
+	for _, kv := range [][2]string{
+		{TraceIDKey, r.traceID}, {"route", r.route}, {"client_ip", r.clientIP}, {UserIDKey, userID}, {TenantKey, tenant},
+	} {
+		if kv[1] != "" {
+			fields = append(fields, kv[0], kv[1])
+		}
+	}
+	return fields
+}
+
+// WithTraceID 将链路ID写入context, 用于下游服务调用及数据库查询
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	info := requestInfo{traceID: traceID}
+	if old, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info = *old
+		info.traceID = traceID
+	}
+	return context.WithValue(ctx, requestInfoKey{}, &info)
+}
+
+// TraceID 获取context中的链路ID, 支持gin上下文
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if id := c.GetString(TraceIDKey); id != "" {
+			return id
+		}
+		if c.Request == nil {
+			return ""
+		}
+		ctx = c.Request.Context()
+	}
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.traceID
+	}
+	return ""
+}
+
+// Context 获取请求的context.Context, 携带链路ID、路由、客户端IP、用户及租户, 用于下游服务调用及数据库查询
+func Context(c *gin.Context) context.Context {
+	info := ginRequestInfo(c)
+	userID, tenant := c.GetString(UserIDKey), c.GetString(TenantKey)
+	info.user = &requestUser{userID: userID, tenant: tenant}
+	return context.WithValue(c.Request.Context(), requestInfoKey{}, info)
+}
+
+// BindContext 将请求信息写入c.Request的context.Context, 用户及租户在请求处理期间从gin上下文读取
+// 返回的函数需在请求结束时调用, 固定用户及租户后解除绑定
+func BindContext(c *gin.Context) func() {
+	info := ginRequestInfo(c)
+	info.user = &requestUser{c: c}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestInfoKey{}, info))
+	return info.user.release
+}
+
+// ginRequestInfo 从gin上下文收集请求信息, 不含用户及租户
+func ginRequestInfo(c *gin.Context) *requestInfo {
+	info := &requestInfo{
+		app:      AppFrom(c),
+		traceID:  TraceID(c),
+		route:    c.FullPath(),
+		clientIP: c.ClientIP(),
+	}
+	if info.route == "" {
+		info.route = c.Request.URL.Path
+	}
+	return info
+}
+
+// Log 获取请求日志, 携带链路ID、路由、客户端IP、用户及租户
+func Log(c *gin.Context) *zap.SugaredLogger {
+	info := ginRequestInfo(c)
+	info.user = &requestUser{userID: c.GetString(UserIDKey), tenant: c.GetString(TenantKey)}
+	return info.app.Logger().With(info.fields()...)
+}
+
+// LogCtx 获取context对应的日志, 携带其中的请求信息, 无请求信息时返回默认应用日志
+func LogCtx(ctx context.Context) *zap.SugaredLogger {
+	if c, ok := ctx.(*gin.Context); ok {
+		return Log(c)
+	}
+	if ctx == nil {
+		return Default().Logger()
+	}
+	info, ok := ctx.Value(requestInfoKey{}).(*requestInfo)
+	if !ok {
+		return Default().Logger()
+	}
+	app := info.app
+	if app == nil {
+		app = Default()
+	}
+	return app.Logger().With(info.fields()...)
+}
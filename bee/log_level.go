@@ -0,0 +1,199 @@
+package bee
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// moduleLevels 按日志名称(模块)覆盖的日志级别, 名称以.分隔层级, 如db匹配db、db.gorm
+type moduleLevels struct {
+	mu     sync.RWMutex
+	global zap.AtomicLevel
+	levels map[string]zapcore.Level
+}
+
+// levelFor 获取日志名称对应的级别, 取最长匹配的模块, 无匹配时使用全局级别
+func (l *moduleLevels) levelFor(name string) zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for name != "" {
+		if lvl, ok := l.levels[name]; ok {
+			return lvl
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return l.global.Level()
+}
+
+// Enabled 任一模块或全局级别允许时即可能输出, 具体由Check按名称判断
+func (l *moduleLevels) Enabled(lvl zapcore.Level) bool {
+	if l.global.Enabled(lvl) {
+		return true
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, min := range l.levels {
+		if lvl >= min {
+			return true
+		}
+	}
+	return false
+}
+
+// set 设置模块级别, module为空时设置全局级别
+func (l *moduleLevels) set(module string, level zapcore.Level) {
+	if module == "" {
+		l.global.SetLevel(level)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.levels == nil {
+		l.levels = make(map[string]zapcore.Level)
+	}
+	l.levels[module] = level
+}
+
+// reset 替换全部模块级别
+func (l *moduleLevels) reset(levels map[string]zapcore.Level) {
+	l.mu.Lock()
+	l.levels = levels
+	l.mu.Unlock()
+}
+
+// remove 删除模块级别, 恢复使用全局级别
+func (l *moduleLevels) remove(module string) {
+	l.mu.Lock()
+	delete(l.levels, module)
+	l.mu.Unlock()
+}
+
+// snapshot 当前级别, 全局级别的key为空字符串
+func (l *moduleLevels) snapshot() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	result := map[string]string{"": l.global.Level().String()}
+	for name, lvl := range l.levels {
+		result[name] = lvl.String()
+	}
+	return result
+}
+
+// levelCore 按日志名称过滤级别的日志核心
+type levelCore struct {
+	zapcore.Core
+	levels *moduleLevels
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.levels.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+// Check 通过全局及模块级别后交由内部核心按各自级别判断
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= c.levels.levelFor(ent.LoggerName) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
+
+// parseModuleLevels 解析模块级别配置
+func parseModuleLevels(modules map[string]string) (map[string]zapcore.Level, error) {
+	levels := make(map[string]zapcore.Level, len(modules))
+	for name, text := range modules {
+		lvl, err := zapcore.ParseLevel(text)
+		if err != nil {
+			return nil, fmt.Errorf("模块<%s>日志级别错误: %w", name, err)
+		}
+		levels[name] = lvl
+	}
+	return levels, nil
+}
+
+// SetLogLevel 运行时调整日志级别, module为空时调整全局级别, 否则调整对应名称日志(Logger().Named)的级别
+func (m *MagicApp) SetLogLevel(module, level string) error {
+	if m.log == nil {
+		return ErrNotInit
+	}
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	m.log.levels.set(module, lvl)
+	return nil
+}
+
+// ResetLogLevel 删除模块日志级别, 恢复使用全局级别
+func (m *MagicApp) ResetLogLevel(module string) {
+	if m.log != nil {
+		m.log.levels.remove(module)
+	}
+}
+
+// LogLevels 当前日志级别, 全局级别的key为空字符串
+func (m *MagicApp) LogLevels() map[string]string {
+	if m.log == nil {
+		return nil
+	}
+	return m.log.levels.snapshot()
+}
+
+// logLevelRequest 调整日志级别请求
+type logLevelRequest struct {
+	Module string `json:"module"` // 模块(日志名称), 为空表示全局
+	Level  string `json:"level"`  // 日志级别, 为空表示删除模块级别
+}
+
+// adminAuth 管理接口鉴权, 校验请求头Authorization: Bearer <app.admin.token>, 未配置令牌时拒绝访问
+func (m *MagicApp) adminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := m.Config().GetString("app.admin.token")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, result{AuthErr, "未配置管理令牌app.admin.token", nil})
+			return
+		}
+		auth := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, result{AuthErr, GetCodeMsg(AuthErr), nil})
+			return
+		}
+		c.Next()
+	}
+}
+
+// getLogLevel 查询日志级别
+func (m *MagicApp) getLogLevel(c *gin.Context) {
+	OkJsonResponse(c, m.LogLevels())
+}
+
+// putLogLevel 调整日志级别
+func (m *MagicApp) putLogLevel(c *gin.Context) {
+	var req logLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorJsonResponse(c, ArgErr, err.Error())
+		return
+	}
+	if req.Level == "" && req.Module != "" {
+		m.ResetLogLevel(req.Module)
+	} else if err := m.SetLogLevel(req.Module, req.Level); err != nil {
+		ErrorJsonResponse(c, ArgErr, err.Error())
+		return
+	}
+	m.Logger().Infof("日志级别调整: module=%q level=%q", req.Module, req.Level)
+	OkJsonResponse(c, m.LogLevels())
+}
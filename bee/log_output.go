@@ -0,0 +1,164 @@
+package bee
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 日志输出类型
+const (
+	OutputStdout  = "stdout"  // 标准输出
+	OutputStderr  = "stderr"  // 标准错误
+	OutputFile    = "file"    // 滚动日志文件
+	OutputSyslog  = "syslog"  // 本地syslog
+	OutputNetwork = "network" // 网络(tcp/udp), 按行发送
+	OutputHTTP    = "http"    // HTTP推送JSON行
+	OutputLoki    = "loki"    // Loki push接口
+	OutputRFC5424 = "rfc5424" // 远程syslog(RFC5424, tcp/udp)
+	OutputFluent  = "fluent"  // Fluent forward协议(tcp)
+)
+
+// logOutput 日志输出配置
+type logOutput struct {
+	Type          string            `mapstructure:"type" validate:"required,oneof=stdout stderr file syslog network http loki rfc5424 fluent"` // 输出类型 stdout/stderr/file/syslog/network/http/loki/rfc5424/fluent
+	Level         string            `mapstructure:"level" validate:"oneof=debug info warn error dpanic panic fatal"`                           // 输出的最低级别, 为空时仅受全局及模块级别限制
+	Encoder       string            `mapstructure:"encoder" validate:"oneof=console json"`                                                     // 编码器 console/json, 为空时按isJsonEncoder, http固定为json
+	FileName      string            `mapstructure:"fileName"`                                                                                  // file: 文件名, 默认使用全局fileName, 如app_error
+	Address       string            `mapstructure:"address"`                                                                                   // syslog: 本地套接字路径, 为空时自动查找; network/rfc5424/fluent: 地址host:port
+	Network       string            `mapstructure:"network" default:"tcp" validate:"oneof=tcp tcp4 tcp6 udp udp4 udp6"`                        // network/rfc5424/fluent: 协议
+	Tag           string            `mapstructure:"tag"`                                                                                       // syslog/rfc5424: 标识, fluent: tag, 默认使用全局fileName
+	URL           string            `mapstructure:"url"`                                                                                       // http/loki: 推送地址, loki如http://loki:3100/loki/api/v1/push
+	Headers       map[string]string `mapstructure:"headers"`                                                                                   // http/loki: 请求头, 如鉴权信息
+	Labels        map[string]string `mapstructure:"labels"`                                                                                    // loki: 流标签, 自动附加level
+	BatchSize     int               `mapstructure:"batchSize" default:"100" validate:"min=1"`                                                  // 远程输出: 每批最多发送条数
+	FlushInterval time.Duration     `mapstructure:"flushInterval" default:"1s" validate:"min=10ms"`                                            // 远程输出: 不足一批时的发送间隔
+	QueueSize     int               `mapstructure:"queueSize" default:"10000" validate:"min=1"`                                                // 远程输出: 队列长度, 满时丢弃日志
+	MaxRetries    int               `mapstructure:"maxRetries" default:"3" validate:"min=0"`                                                   // 远程输出: 发送失败的重试次数, 按指数退避
+	SpillDir      string            `mapstructure:"spillDir"`                                                                                  // 远程输出: 发送失败时的落盘目录, 默认<filePath>/spill, -表示不落盘
+	MaxSpillSize  int64             `mapstructure:"maxSpillSize" default:"104857600" validate:"min=0"`                                         // 远程输出: 落盘文件最大字节数, 0表示不限制
+}
+
+// defaultOutputs 未配置输出时的默认输出: 日志文件及标准输出
+var defaultOutputs = []logOutput{{Type: OutputFile}, {Type: OutputStdout}}
+
+// buildCores 按输出配置创建日志核心
+func (m *MagicLog) buildCores() ([]zapcore.Core, []io.Closer, error) {
+	outputs := m.cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = defaultOutputs
+	}
+
+	var (
+		cores   []zapcore.Core
+		closers []io.Closer
+	)
+	for i, out := range outputs {
+		enabler := zapcore.DebugLevel
+		if out.Level != "" {
+			lvl, err := zapcore.ParseLevel(out.Level)
+			if err != nil {
+				return nil, closers, err
+			}
+			enabler = lvl
+		}
+		encoder := m.getEncoder(out.Encoder == "json" || (out.Encoder == "" && m.cfg.IsJsonEncoder))
+
+		switch out.Type {
+		case OutputStdout:
+			cores = append(cores, zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), enabler))
+		case OutputStderr:
+			cores = append(cores, zapcore.NewCore(encoder, zapcore.Lock(os.Stderr), enabler))
+		case OutputFile:
+			cfg := *m.cfg
+			if out.FileName != "" {
+				cfg.FileName = out.FileName
+			}
+			writer := newRotateWriter(&cfg)
+			closers = append(closers, writer)
+			var ws zapcore.WriteSyncer = writer
+			if m.cfg.Async.Enable && m.cfg.Async.BufferSize > 0 {
+				// 异步模式下文件写入经过缓冲, 由异步队列定时刷新
+				buffered := &zapcore.BufferedWriteSyncer{WS: writer, Size: m.cfg.Async.BufferSize, FlushInterval: m.cfg.Async.FlushInterval}
+				closers = append(closers[:len(closers)-1], bufferedCloser{buffered}, writer)
+				ws = buffered
+			}
+			cores = append(cores, zapcore.NewCore(encoder, ws, enabler))
+		case OutputSyslog:
+			tag := out.Tag
+			if tag == "" {
+				tag = m.cfg.FileName
+			}
+			core, closer, err := newSyslogCore(out.Address, tag, encoder, enabler)
+			if err != nil {
+				return nil, closers, fmt.Errorf("outputs[%d]: %w", i, err)
+			}
+			closers = append(closers, closer)
+			cores = append(cores, core)
+		case OutputNetwork, OutputHTTP, OutputLoki, OutputRFC5424, OutputFluent:
+			if out.Type == OutputHTTP {
+				encoder = m.getEncoder(true)
+			}
+			sink, err := m.newRemoteSink(i, out)
+			if err != nil {
+				return nil, closers, fmt.Errorf("outputs[%d]: %w", i, err)
+			}
+			closers = append(closers, sink)
+			cores = append(cores, &remoteCore{LevelEnabler: enabler, enc: encoder, sink: sink})
+		default:
+			return nil, closers, fmt.Errorf("outputs[%d]: 不支持的输出类型<%s>", i, out.Type)
+		}
+	}
+
+	return cores, closers, nil
+}
+
+// newRemoteSink 创建远程日志发送
+func (m *MagicLog) newRemoteSink(i int, out logOutput) (*remoteSink, error) {
+	tag := out.Tag
+	if tag == "" {
+		tag = m.cfg.FileName
+	}
+	var transport remoteTransport
+	switch out.Type {
+	case OutputHTTP, OutputLoki:
+		if out.URL == "" {
+			return nil, fmt.Errorf("%s输出未配置url", out.Type)
+		}
+		client := &http.Client{Timeout: remoteSendTimeout}
+		transport = &httpTransport{url: out.URL, headers: out.Headers, labels: out.Labels, loki: out.Type == OutputLoki, client: client}
+	case OutputNetwork:
+		if out.Address == "" {
+			return nil, fmt.Errorf("%s输出未配置address", out.Type)
+		}
+		transport = &streamTransport{network: out.Network, address: out.Address, encode: lineEncoder}
+	case OutputRFC5424:
+		if out.Address == "" {
+			return nil, fmt.Errorf("%s输出未配置address", out.Type)
+		}
+		transport = &streamTransport{network: out.Network, address: out.Address, encode: rfc5424Encoder(tag)}
+	case OutputFluent:
+		if out.Address == "" {
+			return nil, fmt.Errorf("%s输出未配置address", out.Type)
+		}
+		if isUDP(out.Network) {
+			return nil, fmt.Errorf("%s输出仅支持tcp", out.Type)
+		}
+		transport = &streamTransport{network: out.Network, address: out.Address, encode: fluentEncoder(tag)}
+	}
+
+	spillDir := out.SpillDir
+	if spillDir == "" {
+		spillDir = filepath.Join(m.cfg.FilePath, "spill")
+	}
+	spillPath := ""
+	if spillDir != "-" {
+		spillPath = filepath.Join(spillDir, fmt.Sprintf("%s_%d_%s.spill", m.cfg.FileName, i, out.Type))
+	}
+	return newRemoteSink(fmt.Sprintf("outputs[%d]", i), transport, out, spillPath, m.dropHook(DropRemote)), nil
+}
@@ -0,0 +1,340 @@
+package bee
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 远程发送的退避时间
+const (
+	remoteBackoffBase = 200 * time.Millisecond
+	remoteBackoffMax  = 30 * time.Second
+)
+
+// remoteFlushTimeout 刷新的最长等待时间, 采集端不可用时不阻塞退出
+const remoteFlushTimeout = 3 * time.Second
+
+// remoteRecord 待发送的日志, 落盘时按JSON行保存
+type remoteRecord struct {
+	Time   time.Time `json:"t"`
+	Level  string    `json:"l"`
+	Logger string    `json:"n,omitempty"`
+	Line   string    `json:"m"`
+}
+
+// remoteTransport 远程日志协议
+type remoteTransport interface {
+	send(batch []remoteRecord) error
+	close() error
+}
+
+// remoteCore 远程日志核心, 编码后放入发送队列, 队列满时丢弃不阻塞业务
+type remoteCore struct {
+	zapcore.LevelEnabler
+	enc  zapcore.Encoder
+	sink *remoteSink
+}
+
+func (c *remoteCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &remoteCore{LevelEnabler: c.LevelEnabler, enc: enc, sink: c.sink}
+}
+
+func (c *remoteCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *remoteCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.sink.push(remoteRecord{Time: ent.Time, Level: ent.Level.String(), Logger: ent.LoggerName, Line: strings.TrimRight(buf.String(), "\n")})
+	buf.Free()
+	return nil
+}
+
+func (c *remoteCore) Sync() error {
+	return c.sink.flush()
+}
+
+// remoteSink 批量发送日志, 失败后按退避重试, 仍失败时落盘, 恢复后优先补发落盘日志
+type remoteSink struct {
+	name       string
+	transport  remoteTransport
+	ch         chan remoteRecord
+	batchSize  int
+	interval   time.Duration
+	maxRetries int
+	spillPath  string // 落盘文件, 为空时不落盘
+	maxSpill   int64
+	onDrop     func()
+
+	flushCh   chan chan struct{}
+	stop      chan struct{}
+	exited    chan struct{}
+	closeOnce sync.Once
+
+	failures     int       // 连续失败次数
+	nextRetry    time.Time // 失败后下次尝试发送的时间
+	replayOffset int64     // 补发文件中已发送的字节数, 重启后从头补发
+}
+
+// newRemoteSink 创建远程发送并启动发送协程
+func newRemoteSink(name string, transport remoteTransport, out logOutput, spillPath string, onDrop func()) *remoteSink {
+	s := &remoteSink{
+		name:       name,
+		transport:  transport,
+		ch:         make(chan remoteRecord, out.QueueSize),
+		batchSize:  out.BatchSize,
+		interval:   out.FlushInterval,
+		maxRetries: out.MaxRetries,
+		spillPath:  spillPath,
+		maxSpill:   out.MaxSpillSize,
+		onDrop:     onDrop,
+		flushCh:    make(chan chan struct{}),
+		stop:       make(chan struct{}),
+		exited:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// push 入队, 队列满时丢弃
+func (s *remoteSink) push(rec remoteRecord) {
+	select {
+	case s.ch <- rec:
+	default:
+		s.onDrop()
+	}
+}
+
+// flush 发送队列中的日志, 发送协程已停止时直接返回, 超过remoteFlushTimeout时不再等待
+func (s *remoteSink) flush() error {
+	timer := time.NewTimer(remoteFlushTimeout)
+	defer timer.Stop()
+	done := make(chan struct{})
+	select {
+	case s.flushCh <- done:
+	case <-s.exited:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("<%s>日志刷新超时", s.name)
+	}
+	select {
+	case <-done:
+	case <-timer.C:
+		return fmt.Errorf("<%s>日志刷新超时", s.name)
+	}
+	return nil
+}
+
+// Close 发送剩余日志后停止
+func (s *remoteSink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.exited
+		_ = s.transport.close()
+	})
+	return nil
+}
+
+// run 按批量或定时发送
+func (s *remoteSink) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	batch := make([]remoteRecord, 0, s.batchSize)
+	drain := func() {
+		for {
+			select {
+			case rec := <-s.ch:
+				batch = append(batch, rec)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case rec := <-s.ch:
+			batch = append(batch, rec)
+			if len(batch) >= s.batchSize {
+				batch = s.send(batch)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				batch = s.send(batch)
+			} else {
+				s.replaySpill()
+			}
+		case done := <-s.flushCh:
+			drain()
+			batch = s.send(batch)
+			close(done)
+		case <-s.stop:
+			drain()
+			s.send(batch)
+			close(s.exited)
+			return
+		}
+	}
+}
+
+// send 发送一批日志, 返回清空后的切片
+func (s *remoteSink) send(batch []remoteRecord) []remoteRecord {
+	if len(batch) == 0 {
+		return batch
+	}
+	// 采集端不可用期间直接落盘, 避免每批都等待重试
+	if time.Now().Before(s.nextRetry) || !s.replaySpill() {
+		s.spill(batch)
+		return batch[:0]
+	}
+	if err := s.deliver(batch, s.maxRetries); err != nil {
+		fmt.Fprintf(os.Stderr, "<%s>日志发送失败: %s\n", s.name, err)
+		s.spill(batch)
+	}
+	return batch[:0]
+}
+
+// deliver 发送并按退避重试
+func (s *remoteSink) deliver(batch []remoteRecord, retries int) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = s.transport.send(batch); err == nil {
+			s.failures, s.nextRetry = 0, time.Time{}
+			return nil
+		}
+		if attempt >= retries {
+			break
+		}
+		select {
+		case <-time.After(backoff(attempt)):
+		case <-s.stop:
+			// 关闭时不再等待重试
+			retries = attempt
+		}
+	}
+	s.failures++
+	s.nextRetry = time.Now().Add(backoff(s.failures))
+	return err
+}
+
+// backoff 指数退避时间
+func backoff(attempt int) time.Duration {
+	d := remoteBackoffBase << min(attempt, 10)
+	return min(d, remoteBackoffMax)
+}
+
+// spill 落盘, 超过上限时丢弃
+func (s *remoteSink) spill(batch []remoteRecord) {
+	if s.spillPath == "" {
+		for range batch {
+			s.onDrop()
+		}
+		return
+	}
+	if info, err := os.Stat(s.spillPath); err == nil && s.maxSpill > 0 && info.Size() >= s.maxSpill {
+		for range batch {
+			s.onDrop()
+		}
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.spillPath), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(s.spillPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "<%s>日志落盘失败: %s\n", s.name, err)
+		return
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range batch {
+		_ = enc.Encode(rec)
+	}
+	_ = w.Flush()
+}
+
+// replaySpill 补发落盘日志, 全部发送成功或无落盘日志时返回true
+// 落盘文件改名为.replay后按批补发并记录已发送位置, 失败时保留剩余部分, 之后落盘的日志在其全部补发后再发送
+func (s *remoteSink) replaySpill() bool {
+	if s.spillPath == "" || time.Now().Before(s.nextRetry) {
+		return s.spillPath == "" || (!exists(s.spillPath) && !exists(s.spillPath+".replay"))
+	}
+	replay := s.spillPath + ".replay"
+	for {
+		if !exists(replay) {
+			if !exists(s.spillPath) {
+				return true
+			}
+			if err := os.Rename(s.spillPath, replay); err != nil {
+				return false
+			}
+			s.replayOffset = 0
+		}
+		if !s.replayFile(replay) {
+			return false
+		}
+		_ = os.Remove(replay)
+		s.replayOffset = 0
+	}
+}
+
+// replayFile 从已发送位置起补发文件, 全部发送成功时返回true
+func (s *remoteSink) replayFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if _, err = f.Seek(s.replayOffset, io.SeekStart); err != nil {
+		return false
+	}
+
+	// 按行读取不限制单行长度, 读取失败时保留文件, 下次从已发送位置继续
+	reader := bufio.NewReaderSize(f, 64<<10)
+	batch := make([]remoteRecord, 0, s.batchSize)
+	offset := s.replayOffset
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(os.Stderr, "<%s>读取落盘日志失败: %s\n", s.name, err)
+			return false
+		}
+		offset += int64(len(line))
+		var rec remoteRecord
+		if json.Unmarshal(line, &rec) == nil {
+			batch = append(batch, rec)
+		}
+		if len(batch) >= s.batchSize || (err == io.EOF && len(batch) > 0) {
+			if s.deliver(batch, 0) != nil {
+				return false
+			}
+			batch = batch[:0]
+		}
+		if len(batch) == 0 {
+			s.replayOffset = offset
+		}
+		if err == io.EOF {
+			return true
+		}
+	}
+}
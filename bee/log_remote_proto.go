@@ -0,0 +1,272 @@
+package bee
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// remoteSendTimeout 远程发送的请求及写入超时
+const remoteSendTimeout = 10 * time.Second
+
+// httpTransport HTTP推送, 请求体为JSON行(application/x-ndjson)或Loki push格式
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	labels  map[string]string // loki: 流标签
+	loki    bool
+	client  *http.Client
+}
+
+func (t *httpTransport) send(batch []remoteRecord) error {
+	var (
+		body        bytes.Buffer
+		contentType = "application/x-ndjson"
+	)
+	if t.loki {
+		contentType = "application/json"
+		if err := json.NewEncoder(&body).Encode(lokiPush(batch, t.labels)); err != nil {
+			return err
+		}
+	} else {
+		for _, rec := range batch {
+			body.WriteString(rec.Line)
+			body.WriteByte('\n')
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, &body)
+	if err != nil {
+		return err
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("<%s>推送日志失败: %s", t.url, resp.Status)
+	}
+	return nil
+}
+
+func (t *httpTransport) close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+// lokiStream Loki push接口的日志流
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// lokiPush 按级别分组为Loki日志流, 标签附加level
+func lokiPush(batch []remoteRecord, labels map[string]string) map[string][]*lokiStream {
+	var streams []*lokiStream
+	byLevel := make(map[string]*lokiStream)
+	for _, rec := range batch {
+		stream, ok := byLevel[rec.Level]
+		if !ok {
+			stream = &lokiStream{Stream: map[string]string{"level": rec.Level}}
+			for k, v := range labels {
+				stream.Stream[k] = v
+			}
+			byLevel[rec.Level] = stream
+			streams = append(streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(rec.Time.UnixNano(), 10), rec.Line})
+	}
+	return map[string][]*lokiStream{"streams": streams}
+}
+
+// streamTransport TCP/UDP推送, 发送失败时关闭连接, 下次发送时重连
+type streamTransport struct {
+	network string
+	address string
+	conn    net.Conn
+	encode  func(batch []remoteRecord, stream bool) [][]byte // 编码为待写入的数据, udp时每段为一个数据报
+}
+
+func (t *streamTransport) send(batch []remoteRecord) error {
+	if t.conn == nil {
+		conn, err := net.DialTimeout(t.network, t.address, remoteSendTimeout)
+		if err != nil {
+			return fmt.Errorf("连接日志地址<%s>失败: %w", t.address, err)
+		}
+		t.conn = conn
+	}
+	_ = t.conn.SetWriteDeadline(time.Now().Add(remoteSendTimeout))
+	for _, p := range t.encode(batch, !isUDP(t.network)) {
+		if _, err := t.conn.Write(p); err != nil {
+			_ = t.conn.Close()
+			t.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *streamTransport) close() error {
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+// isUDP 是否为udp协议
+func isUDP(network string) bool {
+	return network == "udp" || network == "udp4" || network == "udp6"
+}
+
+// rfc5424Encoder 编码为RFC5424 syslog消息, tcp时按RFC6587使用长度前缀分帧
+func rfc5424Encoder(app string) func([]remoteRecord, bool) [][]byte {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	pid := os.Getpid()
+	return func(batch []remoteRecord, stream bool) [][]byte {
+		var buf bytes.Buffer
+		out := make([][]byte, 0, len(batch))
+		for _, rec := range batch {
+			// facility固定为user(1)
+			msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", 8+syslogSeverity(rec.Level),
+				rec.Time.Format("2006-01-02T15:04:05.000000Z07:00"), hostname, app, pid, rec.Line)
+			if !stream {
+				out = append(out, []byte(msg))
+				continue
+			}
+			buf.WriteString(strconv.Itoa(len(msg)))
+			buf.WriteByte(' ')
+			buf.WriteString(msg)
+		}
+		if stream {
+			out = append(out, buf.Bytes())
+		}
+		return out
+	}
+}
+
+// syslogSeverity 日志级别对应的syslog严重程度
+func syslogSeverity(level string) int {
+	lvl, _ := zapcore.ParseLevel(level)
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// lineEncoder 按行编码, udp时每行为一个数据报
+func lineEncoder(batch []remoteRecord, stream bool) [][]byte {
+	if !stream {
+		out := make([][]byte, 0, len(batch))
+		for _, rec := range batch {
+			out = append(out, []byte(rec.Line+"\n"))
+		}
+		return out
+	}
+	var buf bytes.Buffer
+	for _, rec := range batch {
+		buf.WriteString(rec.Line)
+		buf.WriteByte('\n')
+	}
+	return [][]byte{buf.Bytes()}
+}
+
+// fluentEncoder 编码为Fluent forward协议的Forward模式消息: [tag, [[time, record], ...]]
+func fluentEncoder(tag string) func([]remoteRecord, bool) [][]byte {
+	return func(batch []remoteRecord, _ bool) [][]byte {
+		var w msgpackWriter
+		w.array(2)
+		w.str(tag)
+		w.array(len(batch))
+		for _, rec := range batch {
+			w.array(2)
+			w.int(rec.Time.Unix())
+			w.mapHeader(3)
+			w.str("level")
+			w.str(rec.Level)
+			w.str("logger")
+			w.str(rec.Logger)
+			w.str("message")
+			w.str(rec.Line)
+		}
+		return [][]byte{w.Bytes()}
+	}
+}
+
+// msgpackWriter 仅支持forward协议所需类型的msgpack编码
+type msgpackWriter struct {
+	bytes.Buffer
+}
+
+func (w *msgpackWriter) header(fix byte, limit byte, code16 byte, n int) {
+	switch {
+	case n <= int(limit):
+		w.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(code16)
+		w.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		w.WriteByte(code16 + 1)
+		w.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func (w *msgpackWriter) array(n int) { w.header(0x90, 15, 0xdc, n) }
+
+func (w *msgpackWriter) mapHeader(n int) { w.header(0x80, 15, 0xde, n) }
+
+func (w *msgpackWriter) str(s string) {
+	switch n := len(s); {
+	case n <= 31:
+		w.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		w.WriteByte(0xd9)
+		w.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(0xda)
+		w.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		w.WriteByte(0xdb)
+		w.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	w.WriteString(s)
+}
+
+func (w *msgpackWriter) int(v int64) {
+	if v >= 0 && v <= 127 {
+		w.WriteByte(byte(v))
+		return
+	}
+	w.WriteByte(0xd3)
+	w.Write(binary.BigEndian.AppendUint64(nil, uint64(v)))
+}
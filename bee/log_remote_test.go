@@ -0,0 +1,359 @@
+package bee
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector 本地采集端, 收到的数据写入channel
+type collector struct {
+	addr string
+	data chan string
+}
+
+// newHTTPCollector HTTP采集端, 收到的数据为Content-Type及请求体
+func newHTTPCollector(t *testing.T) *collector {
+	c := &collector{data: make(chan string, 10)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.data <- r.Header.Get("Content-Type") + "|" + string(body)
+	}))
+	t.Cleanup(srv.Close)
+	c.addr = srv.URL
+	return c
+}
+
+// newTCPCollector TCP采集端
+func newTCPCollector(t *testing.T) *collector {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	c := &collector{addr: ln.Addr().String(), data: make(chan string, 10)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 64<<10)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			c.data <- string(buf[:n])
+		}
+	}()
+	return c
+}
+
+// newUDPCollector UDP采集端, 每个数据报为一条数据
+func newUDPCollector(t *testing.T) *collector {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pc.Close() })
+	c := &collector{addr: pc.LocalAddr().String(), data: make(chan string, 10)}
+	go func() {
+		buf := make([]byte, 64<<10)
+		for {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			c.data <- string(buf[:n])
+		}
+	}()
+	return c
+}
+
+func TestRemoteTransports(t *testing.T) {
+	tests := []struct {
+		name      string
+		collector func(t *testing.T) *collector
+		transport func(addr string) remoteTransport
+		valid     func(got string) bool
+	}{
+		{
+			name:      "http",
+			collector: newHTTPCollector,
+			transport: func(addr string) remoteTransport {
+				return &httpTransport{url: addr, client: &http.Client{Timeout: time.Second}}
+			},
+			valid: matches(regexp.MustCompile(`^application/x-ndjson\|hello\n$`)),
+		},
+		{
+			name:      "loki",
+			collector: newHTTPCollector,
+			transport: func(addr string) remoteTransport {
+				return &httpTransport{url: addr, labels: map[string]string{"app": "demo"}, loki: true, client: &http.Client{Timeout: time.Second}}
+			},
+			valid: matches(regexp.MustCompile(`^application/json\|\{"streams":\[\{"stream":\{"app":"demo","level":"info"\},"values":\[\["\d+","hello"\]\]\}\]\}`)),
+		},
+		{
+			name:      "rfc5424 tcp",
+			collector: newTCPCollector,
+			transport: func(addr string) remoteTransport {
+				return &streamTransport{network: "tcp", address: addr, encode: rfc5424Encoder("demo")}
+			},
+			valid: func(got string) bool {
+				// 按RFC6587分帧, 长度前缀为消息字节数
+				size, msg, _ := strings.Cut(got, " ")
+				return size == strconv.Itoa(len(msg)) && regexp.MustCompile(`^<14>1 \S+ \S+ demo \d+ - - hello$`).MatchString(msg)
+			},
+		},
+		{
+			name:      "rfc5424 udp",
+			collector: newUDPCollector,
+			transport: func(addr string) remoteTransport {
+				return &streamTransport{network: "udp", address: addr, encode: rfc5424Encoder("demo")}
+			},
+			valid: matches(regexp.MustCompile(`^<14>1 \S+ \S+ demo \d+ - - hello$`)),
+		},
+		{
+			name:      "fluent",
+			collector: newTCPCollector,
+			transport: func(addr string) remoteTransport {
+				return &streamTransport{network: "tcp", address: addr, encode: fluentEncoder("demo")}
+			},
+			valid: func(got string) bool {
+				// [tag, [[time, {level, logger, message}]]], 时间为int64
+				return strings.HasPrefix(got, "\x92\xa4demo\x91\x92\xd3") &&
+					strings.HasSuffix(got, "\x83\xa5level\xa4info\xa6logger\xa0\xa7message\xa5hello")
+			},
+		},
+		{
+			name:      "network tcp",
+			collector: newTCPCollector,
+			transport: func(addr string) remoteTransport {
+				return &streamTransport{network: "tcp", address: addr, encode: lineEncoder}
+			},
+			valid: matches(regexp.MustCompile(`^hello\n$`)),
+		},
+		{
+			name:      "network udp",
+			collector: newUDPCollector,
+			transport: func(addr string) remoteTransport {
+				return &streamTransport{network: "udp", address: addr, encode: lineEncoder}
+			},
+			valid: matches(regexp.MustCompile(`^hello\n$`)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.collector(t)
+			out := logOutput{BatchSize: 10, FlushInterval: time.Hour, QueueSize: 10}
+			sink := newRemoteSink(tt.name, tt.transport(c.addr), out, "", func() {})
+			defer sink.Close()
+
+			sink.push(remoteRecord{Time: time.Now(), Level: "info", Line: "hello"})
+			if err := sink.flush(); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case got := <-c.data:
+				if !tt.valid(got) {
+					t.Errorf("unexpected payload %q", got)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for collector")
+			}
+		})
+	}
+}
+
+// matches 按正则校验
+func matches(re *regexp.Regexp) func(string) bool {
+	return re.MatchString
+}
+
+// memTransport 内存发送, ok为剩余可成功发送的批次数, 小于0时不限制
+type memTransport struct {
+	mu       sync.Mutex
+	ok       int
+	attempts int
+	lines    []string
+}
+
+func (m *memTransport) send(batch []remoteRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+	if m.ok == 0 {
+		return errors.New("unavailable")
+	}
+	m.ok--
+	for _, rec := range batch {
+		m.lines = append(m.lines, rec.Line)
+	}
+	return nil
+}
+
+func (m *memTransport) close() error {
+	return nil
+}
+
+// setOK 设置剩余可成功发送的批次数
+func (m *memTransport) setOK(n int) {
+	m.mu.Lock()
+	m.ok = n
+	m.mu.Unlock()
+}
+
+func TestRemoteSinkSpillReplayOrder(t *testing.T) {
+	transport := &memTransport{}
+	s := &remoteSink{
+		name:      "test",
+		transport: transport,
+		batchSize: 2,
+		spillPath: filepath.Join(t.TempDir(), "test.spill"),
+		onDrop:    func() {},
+	}
+	records := func(lines ...string) []remoteRecord {
+		batch := make([]remoteRecord, 0, len(lines))
+		for _, line := range lines {
+			batch = append(batch, remoteRecord{Time: time.Now(), Level: "info", Line: line})
+		}
+		return batch
+	}
+	steps := []struct {
+		ok    int // 本次可成功发送的批次数
+		lines []string
+	}{
+		{ok: 0, lines: []string{"a", "b"}},  // 采集端不可用, 落盘
+		{ok: 0, lines: []string{"c", "d"}},  // 继续落盘
+		{ok: 1, lines: []string{"e"}},       // 补发a、b后失败, 剩余c、d保留在补发文件, e落盘
+		{ok: -1, lines: []string{"f", "g"}}, // 恢复后依次补发c、d、e, 再发送f、g
+	}
+	for _, step := range steps {
+		transport.setOK(step.ok)
+		s.nextRetry = time.Time{}
+		s.send(records(step.lines...))
+	}
+
+	want := []string{"a", "b", "c", "d", "e", "f", "g"}
+	if !reflect.DeepEqual(transport.lines, want) {
+		t.Errorf("sent %v, want %v", transport.lines, want)
+	}
+	if exists(s.spillPath) || exists(s.spillPath+".replay") {
+		t.Error("spill files remain after replay")
+	}
+}
+
+func TestRemoteSinkReplayLongLine(t *testing.T) {
+	transport := &memTransport{}
+	s := &remoteSink{
+		name:      "test",
+		transport: transport,
+		batchSize: 2,
+		spillPath: filepath.Join(t.TempDir(), "test.spill"),
+		onDrop:    func() {},
+	}
+	// 超过原16M行长度限制的日志
+	long := strings.Repeat("x", 17<<20)
+	s.spill([]remoteRecord{{Line: "a"}, {Line: long}, {Line: "b"}})
+
+	transport.setOK(-1)
+	if !s.replaySpill() {
+		t.Fatal("replaySpill() = false, want true")
+	}
+	if len(transport.lines) != 3 || transport.lines[0] != "a" || transport.lines[1] != long || transport.lines[2] != "b" {
+		t.Errorf("sent %d lines, want a, long line, b", len(transport.lines))
+	}
+	if exists(s.spillPath + ".replay") {
+		t.Error("replay file remains after replay")
+	}
+}
+
+func TestRemoteOutputZeroValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         map[string]any
+		wantRetries int
+		wantSpill   int64
+	}{
+		{name: "absent", raw: map[string]any{"type": OutputHTTP}, wantRetries: 3, wantSpill: 104857600},
+		{name: "zero", raw: map[string]any{"type": OutputHTTP, "maxRetries": 0, "maxSpillSize": 0}, wantRetries: 0, wantSpill: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := DecodeConfig[logOutput]("outputs[0]", tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.MaxRetries != tt.wantRetries || out.MaxSpillSize != tt.wantSpill {
+				t.Errorf("maxRetries = %d, maxSpillSize = %d, want %d, %d", out.MaxRetries, out.MaxSpillSize, tt.wantRetries, tt.wantSpill)
+			}
+		})
+	}
+}
+
+func TestRemoteSinkZeroRetriesUnlimitedSpill(t *testing.T) {
+	transport := &memTransport{}
+	s := &remoteSink{
+		name:      "test",
+		transport: transport,
+		batchSize: 10,
+		spillPath: filepath.Join(t.TempDir(), "test.spill"),
+		onDrop:    func() { t.Error("record dropped, want spilled") },
+	}
+	// maxRetries为0时失败不重试, maxSpillSize为0时落盘不限制大小
+	batch := []remoteRecord{{Line: strings.Repeat("x", 1024)}}
+	s.send(batch)
+	if transport.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", transport.attempts)
+	}
+	s.spill(batch)
+	s.spill(batch)
+	info, err := os.Stat(s.spillPath)
+	if err != nil || info.Size() < 3*1024 {
+		t.Errorf("spill file = %v, %v, want all records spilled", info, err)
+	}
+}
+
+// blockTransport 发送时阻塞, 模拟无响应的采集端
+type blockTransport struct {
+	release chan struct{}
+}
+
+func (b *blockTransport) send([]remoteRecord) error {
+	<-b.release
+	return errors.New("unavailable")
+}
+
+func (b *blockTransport) close() error {
+	return nil
+}
+
+func TestRemoteSinkFlushTimeout(t *testing.T) {
+	transport := &blockTransport{release: make(chan struct{})}
+	out := logOutput{BatchSize: 1, FlushInterval: time.Hour, QueueSize: 10}
+	sink := newRemoteSink("test", transport, out, "", func() {})
+	defer sink.Close()
+	defer close(transport.release)
+
+	sink.push(remoteRecord{Time: time.Now(), Level: "info", Line: "hello"})
+	start := time.Now()
+	err := sink.flush()
+	if err == nil || !strings.Contains(err.Error(), "超时") {
+		t.Errorf("flush() = %v, want timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > remoteFlushTimeout+time.Second {
+		t.Errorf("flush took %s, want at most %s", elapsed, remoteFlushTimeout)
+	}
+}
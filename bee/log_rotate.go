@@ -0,0 +1,312 @@
+package bee
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志滚动周期
+const (
+	RotateDaily  = "daily"  // 按天滚动
+	RotateHourly = "hourly" // 按小时滚动
+	RotateNone   = "none"   // 不按时间滚动, 仅按大小切分
+)
+
+// rotateWriter 按时间周期滚动、周期内按大小切分的日志文件
+// 当前文件为<fileName>_<周期>.log, 切分出的文件为<fileName>_<周期>.<序号>.log, <fileName>.log软链接指向当前文件
+type rotateWriter struct {
+	dir      string
+	name     string
+	rotation string
+	maxSize  int64         // 单文件最大字节数, 0表示不切分
+	backups  int           // 保留历史文件的最大个数, 小于等于0表示不限制
+	maxAge   time.Duration // 保留时长, 小于等于0表示不限制
+	compress bool
+
+	mu       sync.Mutex
+	file     *os.File
+	path     string    // 当前文件路径
+	size     int64     // 当前文件大小
+	rotateAt time.Time // 下次按时间滚动的时间
+	millMu   sync.Mutex
+	closed   bool // 关闭后丢弃写入, 避免重新打开文件后无人关闭
+}
+
+// newRotateWriter 创建滚动日志文件, 并按保留策略清理上次运行遗留的历史文件
+func newRotateWriter(cfg *logConfig) *rotateWriter {
+	w := &rotateWriter{
+		dir:      cfg.FilePath,
+		name:     cfg.FileName,
+		rotation: cfg.Rotation,
+		maxSize:  int64(cfg.MaxSize) << 20,
+		backups:  cfg.MaxBackups,
+		maxAge:   time.Duration(cfg.MaxAge) * 24 * time.Hour,
+		compress: cfg.Compress,
+	}
+	w.path = filepath.Join(w.dir, w.base(time.Now())+".log")
+	w.cleanup()
+
+	return w
+}
+
+// Write 写入日志, 跨周期或超过大小时先滚动
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return len(p), nil
+	}
+	now := time.Now()
+	if w.file == nil {
+		if err := w.open(now); err != nil {
+			return 0, err
+		}
+	} else if !w.rotateAt.IsZero() && !now.Before(w.rotateAt) {
+		if err := w.rotate(now, false); err != nil {
+			return 0, err
+		}
+	} else if w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize && w.size > 0 {
+		if err := w.rotate(now, true); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync 刷盘
+func (w *rotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close 关闭当前文件, 之后的写入被丢弃
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// base 周期对应的文件名前缀
+func (w *rotateWriter) base(t time.Time) string {
+	switch w.rotation {
+	case RotateHourly:
+		return w.name + "_" + t.Format("2006-01-02_15")
+	case RotateNone:
+		return w.name
+	default:
+		return w.name + "_" + t.Format(time.DateOnly)
+	}
+}
+
+// nextRotate 下一个周期的开始时间
+func (w *rotateWriter) nextRotate(t time.Time) time.Time {
+	switch w.rotation {
+	case RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+	case RotateNone:
+		return time.Time{}
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// open 打开当前周期的文件, 已存在时追加写入
+func (w *rotateWriter) open(now time.Time) error {
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return fmt.Errorf("创建日志目录失败: %w", err)
+	}
+	path := filepath.Join(w.dir, w.base(now)+".log")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file, w.path, w.size, w.rotateAt = f, path, info.Size(), w.nextRotate(now)
+	w.link()
+
+	return nil
+}
+
+// link 更新指向当前文件的软链接, 不按时间滚动时文件名固定无需链接
+func (w *rotateWriter) link() {
+	if w.rotation == RotateNone {
+		return
+	}
+	link := filepath.Join(w.dir, w.name+".log")
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(filepath.Base(w.path), tmp); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		_ = os.Remove(tmp)
+	}
+}
+
+// rotate 关闭当前文件并打开新文件, split为true时将当前文件重命名为带序号的切分文件
+func (w *rotateWriter) rotate(now time.Time, split bool) error {
+	if err := w.file.Close(); err != nil {
+		// 文件已不可用, 下次写入时重新打开
+		w.file = nil
+		return err
+	}
+	rotated := w.path
+	if split {
+		prefix := strings.TrimSuffix(w.path, ".log")
+		for i := 1; ; i++ {
+			rotated = fmt.Sprintf("%s.%d.log", prefix, i)
+			if !exists(rotated) && !exists(rotated+".gz") {
+				break
+			}
+		}
+		if err := os.Rename(w.path, rotated); err != nil {
+			// 重命名失败时重新打开原文件继续写入, 避免丢失日志
+			fmt.Fprintf(os.Stderr, "切分日志文件<%s>失败: %s\n", w.path, err)
+			w.file = nil
+			return w.open(now)
+		}
+	}
+	w.file = nil
+	if err := w.open(now); err != nil {
+		return err
+	}
+	// 时钟回拨等情况下仍写入原文件, 无需压缩
+	if rotated != w.path {
+		go w.mill(rotated)
+	}
+
+	return nil
+}
+
+// mill 压缩滚动出的文件并清理过期文件
+func (w *rotateWriter) mill(rotated string) {
+	w.millMu.Lock()
+	defer w.millMu.Unlock()
+	if w.compress {
+		// 文件可能已被之前的清理删除
+		if err := gzipFile(rotated); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "压缩日志文件<%s>失败: %s\n", rotated, err)
+		}
+	}
+	w.cleanup()
+}
+
+// cleanup 按个数及天数清理历史文件
+func (w *rotateWriter) cleanup() {
+	if w.backups <= 0 && w.maxAge <= 0 {
+		return
+	}
+	w.mu.Lock()
+	current := w.path
+	w.mu.Unlock()
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return
+	}
+	type logFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []logFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !w.owns(name) {
+			continue
+		}
+		if !strings.HasSuffix(name, ".log") && !strings.HasSuffix(name, ".log.gz") {
+			continue
+		}
+		path := filepath.Join(w.dir, name)
+		info, err := e.Info()
+		if err != nil || path == current || info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		files = append(files, logFile{path, info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+
+	cutoff := time.Now().Add(-w.maxAge)
+	for i, f := range files {
+		if (w.backups > 0 && i >= w.backups) || (w.maxAge > 0 && f.modTime.Before(cutoff)) {
+			_ = os.Remove(f.path)
+		}
+	}
+}
+
+// owns 是否为当前日志的历史文件, 避免误删同目录下其他日志(如app与app_error)的文件
+func (w *rotateWriter) owns(name string) bool {
+	rest, ok := strings.CutPrefix(name, w.name)
+	if !ok || rest == ".log" {
+		return false
+	}
+	if w.rotation == RotateNone {
+		return strings.HasPrefix(rest, ".")
+	}
+	return len(rest) > 1 && rest[0] == '_' && rest[1] >= '0' && rest[1] <= '9'
+}
+
+// gzipFile 压缩文件为.gz并删除原文件
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	// 保留原文件修改时间, 便于按时间清理
+	_ = os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	_ = src.Close()
+
+	return os.Remove(path)
+}
+
+// exists 文件是否存在
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
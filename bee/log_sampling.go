@@ -0,0 +1,48 @@
+package bee
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// logSampling 日志采样配置, 每个周期内相同级别及内容的日志先输出initial条, 之后每thereafter条输出1条
+// error及以上级别的日志不采样
+type logSampling struct {
+	Initial    int           `mapstructure:"initial" validate:"min=0"`             // 每周期先输出的条数, 0表示不采样
+	Thereafter int           `mapstructure:"thereafter" validate:"min=0"`          // 之后每N条输出1条, 0表示丢弃其余日志
+	Tick       time.Duration `mapstructure:"tick" default:"1s" validate:"min=1ms"` // 采样周期
+}
+
+// samplingCore error以下级别采样, error及以上级别直接输出
+type samplingCore struct {
+	zapcore.Core // 原始核心
+	sampled      zapcore.Core
+}
+
+// newSamplingCore 创建采样核心, 丢弃的日志通过onDrop计数
+func newSamplingCore(core zapcore.Core, cfg logSampling, onDrop func()) zapcore.Core {
+	if cfg.Initial <= 0 {
+		return core
+	}
+	hook := zapcore.SamplerHook(func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+		if dec&zapcore.LogDropped != 0 {
+			onDrop()
+		}
+	})
+	return &samplingCore{
+		Core:    core,
+		sampled: zapcore.NewSamplerWithOptions(core, cfg.Tick, cfg.Initial, cfg.Thereafter, hook),
+	}
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), sampled: c.sampled.With(fields)}
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.ErrorLevel {
+		return c.Core.Check(ent, ce)
+	}
+	return c.sampled.Check(ent, ce)
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+
+package bee
+
+import (
+	"errors"
+	"io"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// newSyslogCore 当前系统不支持syslog
+func newSyslogCore(string, string, zapcore.Encoder, zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	return nil, nil, errors.New("当前系统不支持syslog输出")
+}
@@ -0,0 +1,71 @@
+//go:build !windows && !plan9
+
+package bee
+
+import (
+	"io"
+	"log/syslog"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogCore 本地syslog日志核心, 按日志级别映射syslog严重级别
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	writer *syslog.Writer
+}
+
+// newSyslogCore 连接本地syslog, address为空时自动查找/dev/log等套接字
+func newSyslogCore(address, tag string, enc zapcore.Encoder, enabler zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	network := ""
+	if address != "" {
+		network = "unixgram"
+	}
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &syslogCore{LevelEnabler: enabler, enc: enc, writer: writer}, writer, nil
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, writer: c.writer}
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := buf.String()
+	buf.Free()
+
+	switch {
+	case ent.Level >= zapcore.DPanicLevel:
+		return c.writer.Crit(msg)
+	case ent.Level >= zapcore.ErrorLevel:
+		return c.writer.Err(msg)
+	case ent.Level >= zapcore.WarnLevel:
+		return c.writer.Warning(msg)
+	case ent.Level >= zapcore.InfoLevel:
+		return c.writer.Info(msg)
+	default:
+		return c.writer.Debug(msg)
+	}
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
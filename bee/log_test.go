@@ -0,0 +1,69 @@
+package bee
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRotateWriterClosedDropsWrites(t *testing.T) {
+	dir := t.TempDir()
+	w := newRotateWriter(&logConfig{FilePath: dir, FileName: "app", Rotation: RotateNone})
+	if _, err := w.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 关闭后写入被丢弃, 不重新打开文件
+	if n, err := w.Write([]byte("after\n")); err != nil || n != len("after\n") {
+		t.Errorf("Write() = %d, %v, want dropped without error", n, err)
+	}
+	if w.file != nil {
+		t.Error("file reopened after Close")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil || string(data) != "before\n" {
+		t.Errorf("file = %q, %v, want only the line written before Close", data, err)
+	}
+}
+
+func TestPackageLoggerFollowsReconfigure(t *testing.T) {
+	conf := testViper(t, map[string]any{"app.log.rotation": RotateNone})
+	app := New(WithViper(conf), WithDefault())
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { defaultApp.Store(nil) })
+	pkgLogger := Logger
+
+	// 日志重建期间并发写入包级Logger
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			Logger.Info("concurrent")
+		}
+	}()
+	cfg := *app.log.cfg
+	cfg.FileName = "next"
+	logger, err := app.log.reconfigure(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.setLogger(logger)
+	wg.Wait()
+
+	if Logger != pkgLogger {
+		t.Error("package Logger reassigned after Init")
+	}
+	Logger.Info("after reconfigure")
+	_ = Logger.Sync()
+	data, err := os.ReadFile(filepath.Join(cfg.FilePath, "next.log"))
+	if err != nil || !strings.Contains(string(data), "after reconfigure") {
+		t.Errorf("next.log = %q, %v, want package Logger to write to the rebuilt log", data, err)
+	}
+}
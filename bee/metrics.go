@@ -0,0 +1,75 @@
+package bee
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Counter 单调递增计数器
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Uint64
+}
+
+// Inc 计数加一
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add 计数增加n
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value 当前计数
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// metrics 应用指标
+type metrics struct {
+	mu       sync.Mutex
+	counters map[string]*Counter
+}
+
+// Counter 获取计数器, 不存在时创建, name需符合Prometheus指标命名, 如bee_log_dropped_total
+func (m *MagicApp) Counter(name, help string) *Counter {
+	m.metrics.mu.Lock()
+	defer m.metrics.mu.Unlock()
+	if m.metrics.counters == nil {
+		m.metrics.counters = make(map[string]*Counter)
+	}
+	if c, ok := m.metrics.counters[name]; ok {
+		return c
+	}
+	c := &Counter{name: name, help: help}
+	m.metrics.counters[name] = c
+	return c
+}
+
+// metricsHandler 以Prometheus文本格式输出指标
+func (m *MagicApp) metricsHandler(c *gin.Context) {
+	m.metrics.mu.Lock()
+	counters := make([]*Counter, 0, len(m.metrics.counters))
+	for _, counter := range m.metrics.counters {
+		counters = append(counters, counter)
+	}
+	m.metrics.mu.Unlock()
+	sort.Slice(counters, func(i, j int) bool { return counters[i].name < counters[j].name })
+
+	var sb strings.Builder
+	for _, counter := range counters {
+		if counter.help != "" {
+			fmt.Fprintf(&sb, "# HELP %s %s\n", counter.name, counter.help)
+		}
+		fmt.Fprintf(&sb, "# TYPE %s counter\n%s %d\n", counter.name, counter.name, counter.Value())
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(sb.String()))
+}
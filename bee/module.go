@@ -0,0 +1,86 @@
+package bee
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// Module 功能模块, 每个业务域作为独立模块挂载到自己的路由分组
+type Module interface {
+	Name() string                          // 模块名称, 唯一
+	ConfigKey() string                     // 模块配置前缀key
+	Prefix() string                        // 路由分组前缀
+	Middlewares() []gin.HandlerFunc        // 路由分组中间件
+	Init(app *MagicApp) error              // 初始化, 在路由注册之前执行
+	Routes(g *gin.RouterGroup)             // 路由注册
+	Close(ctx context.Context) error       // 关闭, 应用退出时按注册逆序执行
+	HealthCheck(ctx context.Context) error // 健康检查
+}
+
+// BaseModule 模块默认实现, 嵌入后按需覆盖, 需自行实现Name和Routes
+type BaseModule struct{}
+
+func (BaseModule) ConfigKey() string { return "" }
+
+func (BaseModule) Prefix() string { return "" }
+
+func (BaseModule) Middlewares() []gin.HandlerFunc { return nil }
+
+func (BaseModule) Init(*MagicApp) error { return nil }
+
+func (BaseModule) Close(context.Context) error { return nil }
+
+func (BaseModule) HealthCheck(context.Context) error { return nil }
+
+// RegisterModule 注册模块, 初始化时按注册顺序挂载
+func (m *MagicApp) RegisterModule(mods ...Module) {
+	m.modules = append(m.modules, mods...)
+}
+
+// Modules 已注册的模块
+func (m *MagicApp) Modules() []Module {
+	return append([]Module(nil), m.modules...)
+}
+
+// ModuleConfig 模块配置, 未配置ConfigKey时返回空配置
+func (m *MagicApp) ModuleConfig(mod Module) *viper.Viper {
+	if mod.ConfigKey() != "" {
+		if sub := m.Config().Sub(mod.ConfigKey()); sub != nil {
+			return sub
+		}
+	}
+	return viper.New()
+}
+
+// WithModules 注册模块
+func WithModules(mods ...Module) Option {
+	return func(m *MagicApp) { m.RegisterModule(mods...) }
+}
+
+// initModules 按注册顺序初始化模块并挂载路由分组
+func (m *MagicApp) initModules() error {
+	names := make(map[string]struct{}, len(m.modules))
+	for _, mod := range m.modules {
+		if _, ok := names[mod.Name()]; ok {
+			return fmt.Errorf("模块<%s>重复注册", mod.Name())
+		}
+		names[mod.Name()] = struct{}{}
+
+		if err := mod.Init(m); err != nil {
+			return fmt.Errorf("模块<%s>初始化失败: %w", mod.Name(), err)
+		}
+		group := m.Router.Group(mod.Prefix(), mod.Middlewares()...)
+		mod.Routes(group)
+		if err := m.Register(&Component{Name: "module." + mod.Name(), Stop: mod.Close}); err != nil {
+			return err
+		}
+		m.AddHealthCheck(&HealthCheck{Name: "module." + mod.Name(), Check: mod.HealthCheck})
+		fmt.Printf("[%s] 模块<%s>挂载...ok (%s)\n", time.Now().Format(time.DateTime), mod.Name(), group.BasePath())
+	}
+
+	return nil
+}
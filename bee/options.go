@@ -0,0 +1,241 @@
+package bee
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AppKey 当前应用在gin上下文中的key
+const AppKey = "bee_app"
+
+// defaultApp 默认应用, 包级Logger、Config等辅助函数基于该应用
+var defaultApp atomic.Pointer[MagicApp]
+
+// Option 应用配置项
+type Option func(m *MagicApp)
+
+// New 创建独立的应用实例, 拥有独立的配置、日志及扩展(如数据库注册表)
+func New(opts ...Option) *MagicApp {
+	m := &MagicApp{isolated: true}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// WithDefault 设为默认应用, 包级Logger等指向该应用
+func WithDefault() Option {
+	return func(m *MagicApp) { m.isolated = false }
+}
+
+// WithAddr 运行地址端口
+func WithAddr(addr string) Option {
+	return func(m *MagicApp) { m.Addr = addr }
+}
+
+// WithAdminAddr 管理路由地址端口, 未指定主机时仅监听本机回环地址
+func WithAdminAddr(addr string) Option {
+	return func(m *MagicApp) { m.AdminAddr = addr }
+}
+
+// WithUnixSocket Unix套接字路径
+func WithUnixSocket(path string) Option {
+	return func(m *MagicApp) { m.UnixSocket = path }
+}
+
+// WithConfig 配置文件路径及文件名
+func WithConfig(path, name string) Option {
+	return func(m *MagicApp) { m.ConfPath, m.ConfName = path, name }
+}
+
+// WithConfHotLoading 配置启用热加载
+func WithConfHotLoading() Option {
+	return func(m *MagicApp) { m.ConfHotLoading = true }
+}
+
+// WithEnv 启用环境变量覆盖配置, prefix为空时只覆盖已有的多级配置(顶级key不覆盖)
+func WithEnv(prefix string) Option {
+	return func(m *MagicApp) { m.EnvOverride, m.EnvPrefix = true, prefix }
+}
+
+// WithEnvFile 加载.env文件
+func WithEnvFile(path string) Option {
+	return func(m *MagicApp) { m.EnvFile = path }
+}
+
+// WithConfigSources 追加配置源, 按顺序覆盖配置文件
+func WithConfigSources(srcs ...ConfigSource) Option {
+	return func(m *MagicApp) { m.ConfigSources = append(m.ConfigSources, srcs...) }
+}
+
+// WithViper 使用指定的配置实例作为基础配置, 配置文件与环境变量在其之上覆盖
+func WithViper(conf *viper.Viper) Option {
+	return func(m *MagicApp) { m.conf, m.baseConf = conf, conf }
+}
+
+// WithRunMode 运行模式
+func WithRunMode(mode string) Option {
+	return func(m *MagicApp) { m.RunMode = mode }
+}
+
+// WithRouter 使用指定的路由引擎
+func WithRouter(r *gin.Engine) Option {
+	return func(m *MagicApp) { m.Router = r }
+}
+
+// WithHeartbeat 开启心跳检测
+func WithHeartbeat() Option {
+	return func(m *MagicApp) { m.IsHeartbeat = true }
+}
+
+// WithRoutes 路由注册
+func WithRoutes(fn func(r *gin.Engine)) Option {
+	return func(m *MagicApp) { m.RegRouteFun = fn }
+}
+
+// WithAdminRoutes 管理路由注册
+func WithAdminRoutes(fn func(r *gin.Engine)) Option {
+	return func(m *MagicApp) { m.RegAdminRouteFun = fn }
+}
+
+// WithShutdown 优雅关闭超时时间及关闭前的等待时间
+func WithShutdown(timeout, preStopDelay time.Duration) Option {
+	return func(m *MagicApp) { m.ShutdownTimeout, m.PreStopDelay = timeout, preStopDelay }
+}
+
+// WithGracefulRestart 开启平滑重启
+func WithGracefulRestart(timeout time.Duration) Option {
+	return func(m *MagicApp) { m.GracefulRestart, m.RestartTimeout = true, timeout }
+}
+
+// WithComponents 注册生命周期组件
+func WithComponents(cs ...*Component) Option {
+	return func(m *MagicApp) { _ = m.Register(cs...) }
+}
+
+// WithExitAfter 程序结束后的操作
+func WithExitAfter(fn func()) Option {
+	return func(m *MagicApp) { m.ExitAfter = fn }
+}
+
+// Default 获取默认应用, 未设置时创建一个空应用
+func Default() *MagicApp {
+	if m := defaultApp.Load(); m != nil {
+		return m
+	}
+	defaultApp.CompareAndSwap(nil, &MagicApp{placeholder: true})
+	return defaultApp.Load()
+}
+
+// Config 默认应用的配置
+func Config() *viper.Viper {
+	return Default().Config()
+}
+
+// AppFrom 获取处理请求的应用, 未绑定时返回默认应用
+func AppFrom(c *gin.Context) *MagicApp {
+	if v, ok := c.Get(AppKey); ok {
+		if m, ok := v.(*MagicApp); ok {
+			return m
+		}
+	}
+	return Default()
+}
+
+// Config 应用的配置实例
+func (m *MagicApp) Config() *viper.Viper {
+	m.mu.RLock()
+	conf := m.conf
+	m.mu.RUnlock()
+	if conf != nil {
+		return conf
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conf == nil {
+		m.conf = viper.New()
+	}
+	return m.conf
+}
+
+// Logger 应用的日志实例, 未初始化时返回空日志
+func (m *MagicApp) Logger() *zap.SugaredLogger {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.logger == nil {
+		return zap.NewNop().Sugar()
+	}
+	return m.logger
+}
+
+// setLogger 设置应用日志, 日志重建时由配置监听协程调用, 不修改包级Logger
+func (m *MagicApp) setLogger(logger *zap.SugaredLogger) {
+	m.mu.Lock()
+	m.logger = logger
+	m.mu.Unlock()
+}
+
+// appCore 写入应用当前日志的核心, 包级Logger使用, 日志重建后无需重新获取
+type appCore struct {
+	app    *MagicApp
+	fields []zapcore.Field
+}
+
+// current 应用当前日志的核心
+func (c *appCore) current() zapcore.Core {
+	core := c.app.Logger().Desugar().Core()
+	if len(c.fields) > 0 {
+		core = core.With(c.fields)
+	}
+	return core
+}
+
+func (c *appCore) Enabled(level zapcore.Level) bool {
+	return c.app.Logger().Desugar().Core().Enabled(level)
+}
+
+func (c *appCore) With(fields []zapcore.Field) zapcore.Core {
+	return &appCore{app: c.app, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *appCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.current().Check(ent, ce)
+}
+
+func (c *appCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.current().Write(ent, fields)
+}
+
+func (c *appCore) Sync() error {
+	return c.app.Logger().Sync()
+}
+
+// Extension 获取应用扩展(如数据库注册表), 不存在时通过create创建
+func (m *MagicApp) Extension(key string, create func() any) any {
+	m.extMu.Lock()
+	defer m.extMu.Unlock()
+	if m.extensions == nil {
+		m.extensions = make(map[string]any)
+	}
+	if v, ok := m.extensions[key]; ok {
+		return v
+	}
+	v := create()
+	m.extensions[key] = v
+	return v
+}
+
+// bindApp 将应用绑定到请求上下文
+func (m *MagicApp) bindApp() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(AppKey, m)
+		c.Next()
+	}
+}
@@ -0,0 +1,163 @@
+package bee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 平滑重启时父子进程间传递监听使用的环境变量
+const (
+	envInheritFds   = "BEE_INHERIT_FDS"   // 继承的监听数量
+	envInheritNames = "BEE_INHERIT_NAMES" // 继承的监听名称, 冒号分隔
+	envReadyFd      = "BEE_READY_FD"      // 子进程就绪通知管道
+)
+
+// defaultRestartTimeout 默认等待子进程就绪的超时时间
+const defaultRestartTimeout = 30 * time.Second
+
+// inheritedListeners 获取父进程平滑重启时传入的监听
+func inheritedListeners() (map[string][]net.Listener, error) {
+	n, err := strconv.Atoi(os.Getenv(envInheritFds))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv(envInheritNames), ":")
+	_ = os.Unsetenv(envInheritFds)
+	_ = os.Unsetenv(envInheritNames)
+
+	return fileListeners(3, n, names)
+}
+
+// notifyParentReady 通知父进程子进程已就绪
+func notifyParentReady() {
+	fd, err := strconv.Atoi(os.Getenv(envReadyFd))
+	if err != nil {
+		return
+	}
+	_ = os.Unsetenv(envReadyFd)
+	f := os.NewFile(uintptr(fd), "ready")
+	_, _ = f.Write([]byte("ready"))
+	_ = f.Close()
+}
+
+// restart 启动新进程并传递监听, 等待新进程就绪, ctx结束时放弃重启
+func (m *MagicApp) restart(ctx context.Context, servers []*server) error {
+	var files []*os.File
+	var names []string
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, s := range servers {
+		for _, ln := range s.lns {
+			filer, ok := ln.(interface{ File() (*os.File, error) })
+			if !ok {
+				return fmt.Errorf("监听%s不支持传递", ln.Addr())
+			}
+			f, err := filer.File()
+			if err != nil {
+				return fmt.Errorf("获取监听%s文件描述符失败: %w", ln.Addr(), err)
+			}
+			files = append(files, f)
+			names = append(names, s.name)
+		}
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyR.Close()
+
+	path, err := os.Executable()
+	if err != nil {
+		_ = readyW.Close()
+		return err
+	}
+	cmd := &exec.Cmd{
+		Path:       path,
+		Args:       os.Args,
+		Env:        restartEnv(os.Environ(), len(files), names),
+		Stdin:      os.Stdin,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		ExtraFiles: append(files, readyW),
+	}
+	err = cmd.Start()
+	_ = readyW.Close()
+	if err != nil {
+		return fmt.Errorf("启动新进程失败: %w", err)
+	}
+
+	timeout := m.RestartTimeout
+	if timeout <= 0 {
+		timeout = defaultRestartTimeout
+	}
+	if err = waitReady(ctx, readyR, timeout); err != nil {
+		_ = cmd.Process.Kill()
+		_, _ = cmd.Process.Wait()
+		return err
+	}
+
+	// 新进程已接管, 关闭时保留Unix套接字文件
+	for _, s := range servers {
+		for _, ln := range s.lns {
+			if ul, ok := ln.(*net.UnixListener); ok {
+				ul.SetUnlinkOnClose(false)
+			}
+		}
+	}
+	fmt.Printf("[%s] 新进程已就绪: pid=%d\n", time.Now().Format(time.DateTime), cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// restartEnv 生成新进程的环境变量, 去掉本进程继承的监听变量后写入传递的监听
+func restartEnv(environ []string, n int, names []string) []string {
+	env := make([]string, 0, len(environ)+3)
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		switch name {
+		case envInheritFds, envInheritNames, envReadyFd, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES":
+		default:
+			env = append(env, kv)
+		}
+	}
+
+	return append(env,
+		fmt.Sprintf("%s=%d", envInheritFds, n),
+		fmt.Sprintf("%s=%s", envInheritNames, strings.Join(names, ":")),
+		fmt.Sprintf("%s=%d", envReadyFd, 3+n),
+	)
+}
+
+// waitReady 等待新进程通过管道通知就绪
+func waitReady(ctx context.Context, r io.Reader, timeout time.Duration) error {
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			ready <- errors.New("新进程未就绪即退出")
+			return
+		}
+		ready <- nil
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-ready:
+		return err
+	case <-timer.C:
+		return errors.New("等待新进程就绪超时")
+	case <-ctx.Done():
+		return fmt.Errorf("等待新进程就绪时收到退出信号: %w", ctx.Err())
+	}
+}
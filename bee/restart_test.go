@@ -0,0 +1,68 @@
+package bee
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestartEnv(t *testing.T) {
+	environ := []string{
+		"PATH=/bin",
+		"BEE_INHERIT_FDS=1",
+		"BEE_INHERIT_NAMES=public",
+		"BEE_READY_FD=4",
+		"BEE_MASTER_KEY=secret",
+		"BEE_MASTER_KEY_FILE=/run/key",
+		"LISTEN_PID=1",
+		"LISTEN_FDS=1",
+		"LISTEN_FDNAMES=public",
+		"LISTEN_ADDR=:8080",
+	}
+	want := []string{
+		"PATH=/bin",
+		"BEE_MASTER_KEY=secret",
+		"BEE_MASTER_KEY_FILE=/run/key",
+		"LISTEN_ADDR=:8080",
+		"BEE_INHERIT_FDS=2",
+		"BEE_INHERIT_NAMES=public:admin",
+		"BEE_READY_FD=5",
+	}
+	// 只去掉监听传递相关的变量, 其他BEE_、LISTEN_前缀的业务变量保留
+	if got := restartEnv(environ, 2, []string{PublicListener, AdminListener}); !reflect.DeepEqual(got, want) {
+		t.Errorf("restartEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		reader  func() io.Reader
+		wantErr string
+	}{
+		{name: "ready", ctx: context.Background(), reader: func() io.Reader { return strings.NewReader("ready") }},
+		{name: "child exited", ctx: context.Background(), reader: func() io.Reader { return strings.NewReader("") }, wantErr: "未就绪即退出"},
+		{name: "timeout", ctx: context.Background(), reader: blockingReader, wantErr: "超时"},
+		{name: "canceled", ctx: canceled, reader: blockingReader, wantErr: "退出信号"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := waitReady(tt.ctx, tt.reader(), 100*time.Millisecond)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("waitReady() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// blockingReader 不会返回数据的读取端, 模拟未就绪的新进程
+func blockingReader() io.Reader {
+	r, _ := io.Pipe()
+	return r
+}
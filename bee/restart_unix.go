@@ -0,0 +1,11 @@
+//go:build !windows
+
+package bee
+
+import (
+	"os"
+	"syscall"
+)
+
+// restartSignals 触发平滑重启的信号
+var restartSignals = []os.Signal{syscall.SIGUSR2, syscall.SIGHUP}
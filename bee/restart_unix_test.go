@@ -0,0 +1,61 @@
+//go:build !windows
+
+package bee
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRestartChild 平滑重启测试启动的新进程, 接管监听后通知就绪并应答一个连接
+func TestRestartChild(t *testing.T) {
+	if os.Getenv(envInheritFds) == "" {
+		t.Skip("only runs as the restarted process")
+	}
+	inherited, err := inheritedListeners()
+	if err != nil || len(inherited[PublicListener]) != 1 {
+		t.Fatalf("inheritedListeners() = %v, %v", inherited, err)
+	}
+	ln := inherited[PublicListener][0]
+	defer ln.Close()
+	notifyParentReady()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte("child"))
+}
+
+func TestRestartHandoff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestRestartChild$"}
+	defer func() { os.Args = args }()
+
+	app := New()
+	app.RestartTimeout = 10 * time.Second
+	if err = app.restart(context.Background(), []*server{{name: PublicListener, lns: []net.Listener{ln}}}); err != nil {
+		t.Fatal(err)
+	}
+	// 旧进程关闭监听后, 新连接由新进程应答
+	_ = ln.Close()
+	conn, err := net.DialTimeout("tcp", ln.Addr().String(), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, err := io.ReadAll(conn)
+	if err != nil || string(got) != "child" {
+		t.Errorf("response = %q, %v, want child", got, err)
+	}
+}
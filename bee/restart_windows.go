@@ -0,0 +1,8 @@
+//go:build windows
+
+package bee
+
+import "os"
+
+// restartSignals Windows不支持平滑重启
+var restartSignals []os.Signal
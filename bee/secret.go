@@ -0,0 +1,198 @@
+package bee
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// 配置值中的密文前缀及主密钥来源
+const (
+	encPrefix        = "enc:"
+	EnvMasterKey     = "BEE_MASTER_KEY"      // 主密钥(base64), 优先于主密钥文件
+	EnvMasterKeyFile = "BEE_MASTER_KEY_FILE" // 主密钥文件路径, 文件内容为base64
+)
+
+// secretRef 配置引用 ${env:NAME} / ${file:/run/secrets/x}
+var secretRef = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// GenerateMasterKey 生成base64编码的AES-256主密钥
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseMasterKey 解析base64编码的主密钥, 长度需为16、24或32字节
+func ParseMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("主密钥不是合法的base64: %w", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("主密钥长度需为16、24或32字节, 当前%d字节", len(key))
+}
+
+// EncryptValue 使用AES-GCM加密配置值, 返回enc:前缀的密文
+func EncryptValue(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue 解密enc:前缀的配置值
+func DecryptValue(key []byte, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encPrefix))
+	if err != nil {
+		return "", fmt.Errorf("密文不是合法的base64: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("密文长度错误")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("解密失败, 请检查主密钥")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// masterKey 获取主密钥, 优先使用应用配置的密钥文件, 其次环境变量
+func (m *MagicApp) masterKey() ([]byte, error) {
+	if encoded := os.Getenv(EnvMasterKey); encoded != "" && m.MasterKeyFile == "" {
+		return ParseMasterKey(encoded)
+	}
+	path := m.MasterKeyFile
+	if path == "" {
+		path = os.Getenv(EnvMasterKeyFile)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("未配置主密钥, 请设置%s或%s", EnvMasterKey, EnvMasterKeyFile)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取主密钥文件失败: %w", err)
+	}
+	return ParseMasterKey(string(content))
+}
+
+// secretResolver 解析配置中的引用及密文
+type secretResolver struct {
+	app      *MagicApp
+	key      []byte // 主密钥, 首次遇到密文时加载
+	errs     []error
+	resolved map[string]bool // 值来自引用或密文的配置key
+}
+
+// resolveSecrets 解析配置中的${env:}、${file:}引用及enc:密文, 同时返回值被解析的配置key
+func (m *MagicApp) resolveSecrets(settings map[string]any) (map[string]any, map[string]bool, error) {
+	r := &secretResolver{app: m, resolved: make(map[string]bool)}
+	resolved := r.walk("", settings).(map[string]any)
+	return resolved, r.resolved, errors.Join(r.errs...)
+}
+
+func (r *secretResolver) walk(path string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			out[k] = r.walk(p, item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = r.walk(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for i, item := range v {
+			out[i] = r.resolve(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+		return out
+	case string:
+		return r.resolve(path, v)
+	default:
+		return value
+	}
+}
+
+// resolve 解析单个字符串值
+func (r *secretResolver) resolve(path, value string) string {
+	if strings.HasPrefix(value, encPrefix) || strings.Contains(value, "${") {
+		// 列表元素按所在key记录
+		key, _, _ := strings.Cut(path, "[")
+		r.resolved[key] = true
+	}
+	if strings.HasPrefix(value, encPrefix) {
+		if r.key == nil {
+			key, err := r.app.masterKey()
+			if err != nil {
+				r.errs = append(r.errs, fmt.Errorf("%s: %w", path, err))
+				return value
+			}
+			r.key = key
+		}
+		plain, err := DecryptValue(r.key, value)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("%s: %w", path, err))
+			return value
+		}
+		return plain
+	}
+	if !strings.Contains(value, "${") {
+		return value
+	}
+
+	return secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+		match := secretRef.FindStringSubmatch(ref)
+		kind, name := match[1], strings.TrimSpace(match[2])
+		if kind == "env" {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				r.errs = append(r.errs, fmt.Errorf("%s: 环境变量<%s>未设置", path, name))
+			}
+			return v
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("%s: 读取文件失败: %w", path, err))
+			return ""
+		}
+		return strings.TrimRight(string(content), "\r\n")
+	})
+}
@@ -0,0 +1,166 @@
+package bee
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultShutdownTimeout 默认优雅关闭超时时间
+const defaultShutdownTimeout = 5 * time.Second
+
+// drainState 请求排空状态
+type drainState struct {
+	ready    atomic.Bool  // 是否就绪
+	active   atomic.Int64 // 处理中的请求数
+	hijacked atomic.Int64 // 未关闭的被劫持连接数(websocket等)
+	connMu   sync.Mutex
+	conns    map[*trackedConn]struct{} // 未关闭的被劫持连接
+}
+
+// trackedListener 记录接受的连接, 用于跟踪被劫持连接的关闭
+type trackedListener struct {
+	net.Listener
+	drain *drainState
+}
+
+func (l *trackedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &trackedConn{Conn: conn, drain: l.drain}, nil
+}
+
+// trackedConn 被劫持后关闭时从排空状态中移除
+type trackedConn struct {
+	net.Conn
+	drain    *drainState
+	hijacked atomic.Bool
+	once     sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	if c.hijacked.Load() {
+		c.once.Do(func() { c.drain.release(c) })
+	}
+	return err
+}
+
+// hijack 记录被劫持的连接
+func (d *drainState) hijack(c *trackedConn) {
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+	if d.conns == nil {
+		d.conns = make(map[*trackedConn]struct{})
+	}
+	d.conns[c] = struct{}{}
+	c.hijacked.Store(true)
+	d.hijacked.Add(1)
+}
+
+// release 移除已关闭的被劫持连接
+func (d *drainState) release(c *trackedConn) {
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+	delete(d.conns, c)
+	d.hijacked.Add(-1)
+}
+
+// closeHijacked 关闭所有被劫持的连接, 返回关闭的个数
+func (d *drainState) closeHijacked() int {
+	d.connMu.Lock()
+	conns := make([]*trackedConn, 0, len(d.conns))
+	for c := range d.conns {
+		conns = append(conns, c)
+	}
+	d.connMu.Unlock()
+	for _, c := range conns {
+		_ = c.Close()
+	}
+	return len(conns)
+}
+
+// trackRequests 统计处理中的请求
+func (m *MagicApp) trackRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.drain.active.Add(1)
+		defer m.drain.active.Add(-1)
+		c.Next()
+	}
+}
+
+// trackConnState 统计被劫持的连接, 需配合trackedListener使用
+func (m *MagicApp) trackConnState(conn net.Conn, state http.ConnState) {
+	if state != http.StateHijacked {
+		return
+	}
+	if tc, ok := conn.(*tls.Conn); ok {
+		conn = tc.NetConn()
+	}
+	if c, ok := conn.(*trackedConn); ok {
+		m.drain.hijack(c)
+	}
+}
+
+// waitDrain 等待处理中的请求及被劫持的连接结束, 超时时关闭被劫持的连接并返回错误
+func (m *MagicApp) waitDrain(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for m.drain.active.Load() > 0 || m.drain.hijacked.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			closed := m.drain.closeHijacked()
+			return fmt.Errorf("等待请求结束超时, 剩余请求数: %d, 强制关闭连接数: %d", m.drain.active.Load(), closed)
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// forceExitOnSignal 再次收到退出信号时强制退出
+func forceExitOnSignal() func() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ch:
+			fmt.Println("Server forced to exit")
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// Ready 服务是否就绪
+func (m *MagicApp) Ready() bool {
+	return m.drain.ready.Load()
+}
+
+// ActiveRequests 处理中的请求数
+func (m *MagicApp) ActiveRequests() int64 {
+	return m.drain.active.Load()
+}
+
+// HijackedConns 未关闭的被劫持连接数
+func (m *MagicApp) HijackedConns() int64 {
+	return m.drain.hijacked.Load()
+}
@@ -0,0 +1,244 @@
+package bee
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TLSClientKey 客户端证书身份在gin上下文中的key
+const TLSClientKey = "tls_client"
+
+// certCheckInterval 证书文件变更检查间隔
+const certCheckInterval = time.Second
+
+// tlsConfig TLS配置
+type tlsConfig struct {
+	Enable       bool     `mapstructure:"enable"`                                                              // 是否启用HTTPS
+	CertFile     string   `mapstructure:"certFile"`                                                            // 证书文件路径
+	KeyFile      string   `mapstructure:"keyFile"`                                                             // 私钥文件路径
+	MinVersion   string   `mapstructure:"minVersion" validate:"oneof=1.0 1.1 1.2 1.3"`                         // 最低TLS版本 1.0/1.1/1.2/1.3, 默认1.2
+	CipherSuites []string `mapstructure:"cipherSuites"`                                                        // 加密套件名称, 如TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+	ClientCAFile string   `mapstructure:"clientCAFile"`                                                        // 客户端CA证书路径, 配置后开启双向认证
+	ClientAuth   string   `mapstructure:"clientAuth" validate:"oneof=request require verify requireAndVerify"` // 客户端认证模式 request/require/verify/requireAndVerify, 默认requireAndVerify
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"request":          tls.RequestClientCert,
+	"require":          tls.RequireAnyClientCert,
+	"verify":           tls.VerifyClientCertIfGiven,
+	"requireAndVerify": tls.RequireAndVerifyClientCert,
+}
+
+// build 生成tls.Config
+func (t *tlsConfig) build(app *MagicApp) (*tls.Config, error) {
+	if t.CertFile == "" || t.KeyFile == "" {
+		return nil, errors.New("TLS证书或私钥路径未配置")
+	}
+	base := &tls.Config{MinVersion: tls.VersionTLS12, NextProtos: []string{"h2", "http/1.1"}}
+	if t.MinVersion != "" {
+		version, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("不支持的TLS版本: %s", t.MinVersion)
+		}
+		base.MinVersion = version
+	}
+	if len(t.CipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites[s.Name] = s.ID
+		}
+		for _, name := range t.CipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("不支持的加密套件: %s", name)
+			}
+			base.CipherSuites = append(base.CipherSuites, id)
+		}
+	}
+	if t.ClientCAFile != "" {
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+		if t.ClientAuth != "" {
+			auth, ok := clientAuthTypes[t.ClientAuth]
+			if !ok {
+				return nil, fmt.Errorf("不支持的客户端认证模式: %s", t.ClientAuth)
+			}
+			base.ClientAuth = auth
+		}
+	}
+
+	reloader := &certReloader{app: app, cfg: t, base: base}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:         base.MinVersion,
+		NextProtos:         base.NextProtos,
+		GetCertificate:     reloader.getCertificate,
+		GetConfigForClient: reloader.getConfigForClient,
+	}, nil
+}
+
+// certReloader 证书热加载, 证书文件变化后自动重新加载
+type certReloader struct {
+	app     *MagicApp
+	cfg     *tlsConfig
+	base    *tls.Config
+	mu      sync.RWMutex
+	current *tls.Config // 当前生效的配置
+	modTime time.Time   // 已加载文件的最新修改时间
+	checked time.Time   // 上次检查时间
+}
+
+// load 加载证书文件
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("加载TLS证书失败: %w", err)
+	}
+	cfg := r.base.Clone()
+	cfg.Certificates = []tls.Certificate{cert}
+	if r.cfg.ClientCAFile != "" {
+		caPem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("读取客户端CA证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return errors.New("客户端CA证书解析失败")
+		}
+		cfg.ClientCAs = pool
+	}
+
+	r.mu.Lock()
+	r.current = cfg
+	r.modTime = r.latestModTime()
+	r.checked = time.Now()
+	r.mu.Unlock()
+
+	return nil
+}
+
+// latestModTime 证书相关文件的最新修改时间
+func (r *certReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, f := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if f == "" {
+			continue
+		}
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest
+}
+
+// getConfigForClient 握手时返回当前配置, 文件变化时重新加载
+func (r *certReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.RLock()
+	current, modTime, checked := r.current, r.modTime, r.checked
+	r.mu.RUnlock()
+	if time.Since(checked) < certCheckInterval {
+		return current, nil
+	}
+
+	r.mu.Lock()
+	r.checked = time.Now()
+	r.mu.Unlock()
+	if latest := r.latestModTime(); latest.After(modTime) {
+		if err := r.load(); err != nil {
+			// 新证书加载失败时继续使用旧证书, 记录失败文件的修改时间, 文件再次变化后才重试
+			r.mu.Lock()
+			r.modTime = latest
+			r.mu.Unlock()
+			r.app.Logger().Error(err)
+			return current, nil
+		}
+		r.mu.RLock()
+		current = r.current
+		r.mu.RUnlock()
+	}
+
+	return current, nil
+}
+
+// getCertificate 返回当前证书
+func (r *certReloader) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cfg, err := r.getConfigForClient(hello)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg.Certificates[0], nil
+}
+
+// ClientIdentity 客户端证书身份
+type ClientIdentity struct {
+	CommonName   string   // 通用名称
+	Organization []string // 组织
+	DNSNames     []string // DNS名称
+	Emails       []string // 邮箱
+	SerialNumber string   // 证书序列号
+	Issuer       string   // 签发者
+}
+
+// tlsIdentity 将已验证的客户端证书身份写入上下文
+func tlsIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0 && len(c.Request.TLS.VerifiedChains[0]) > 0 {
+			cert := c.Request.TLS.VerifiedChains[0][0]
+			c.Set(TLSClientKey, &ClientIdentity{
+				CommonName:   cert.Subject.CommonName,
+				Organization: cert.Subject.Organization,
+				DNSNames:     cert.DNSNames,
+				Emails:       cert.EmailAddresses,
+				SerialNumber: cert.SerialNumber.String(),
+				Issuer:       cert.Issuer.String(),
+			})
+		}
+		c.Next()
+	}
+}
+
+// GetClientIdentity 获取已验证的客户端证书身份
+func GetClientIdentity(c *gin.Context) (*ClientIdentity, bool) {
+	v, ok := c.Get(TLSClientKey)
+	if !ok {
+		return nil, false
+	}
+	identity, ok := v.(*ClientIdentity)
+	return identity, ok
+}
+
+// initTLS 初始化TLS配置
+func (m *MagicApp) initTLS() error {
+	config, err := LoadConfigFrom[tlsConfig](m.Config(), "app.tls")
+	if err != nil {
+		return err
+	}
+	if !config.Enable {
+		return nil
+	}
+	tlsCfg, err := config.build(m)
+	if err != nil {
+		return err
+	}
+	m.tlsConfig = tlsCfg
+	m.Router.Use(tlsIdentity())
+	fmt.Printf("[%s] 初始化TLS...ok\n", time.Now().Format(time.DateTime))
+
+	return nil
+}
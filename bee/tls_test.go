@@ -0,0 +1,245 @@
+package bee
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testCA 测试用CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+	file string
+}
+
+// newTestCA 生成自签名CA并写入dir
+func newTestCA(t *testing.T, dir string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{cert: cert, key: key, pool: x509.NewCertPool(), file: filepath.Join(dir, "ca.pem")}
+	ca.pool.AddCert(cert)
+	writePEM(t, ca.file, "CERTIFICATE", der)
+	return ca
+}
+
+// issue 签发证书, 返回tls.Certificate并将证书和私钥写入certFile、keyFile
+func (ca *testCA) issue(t *testing.T, cn string, serial int64, usage x509.ExtKeyUsage, certFile, keyFile string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certFile != "" {
+		writePEM(t, certFile, "CERTIFICATE", der)
+		writePEM(t, keyFile, "EC PRIVATE KEY", keyDer)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writePEM 写入PEM文件
+func writePEM(t *testing.T, file, typ string, der []byte) {
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// touch 将文件修改时间设置为当前时间之后, 模拟证书轮换
+func touch(t *testing.T, files ...string) {
+	future := time.Now().Add(time.Minute)
+	for _, f := range files {
+		if err := os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTLSServer 使用tlsConfig启动HTTPS测试服务, 返回客户端证书的CommonName, 无客户端证书时返回"-"
+func newTLSServer(t *testing.T, cfg *tlsConfig) *httptest.Server {
+	tlsCfg, err := cfg.build(New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.Use(tlsIdentity())
+	router.GET("/", func(c *gin.Context) {
+		identity, ok := GetClientIdentity(c)
+		if !ok {
+			c.String(http.StatusOK, "-")
+			return
+		}
+		c.String(http.StatusOK, identity.CommonName)
+	})
+	srv := httptest.NewUnstartedServer(router)
+	srv.TLS = tlsCfg
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// tlsGet 发起HTTPS请求, 返回响应内容及服务端证书
+func tlsGet(srv *httptest.Server, roots *x509.CertPool, certs ...tls.Certificate) (string, *x509.Certificate, error) {
+	client := &http.Client{
+		Timeout: 2 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+			DisableKeepAlives: true,
+		},
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(body), resp.TLS.PeerCertificates[0], nil
+}
+
+func TestTLSServe(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth, certFile, keyFile)
+	srv := newTLSServer(t, &tlsConfig{Enable: true, CertFile: certFile, KeyFile: keyFile})
+
+	body, peer, err := tlsGet(srv, ca.pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "-" || peer.Subject.CommonName != "server" {
+		t.Errorf("body = %q, server cert = %q, want -, server", body, peer.Subject.CommonName)
+	}
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth, certFile, keyFile)
+	srv := newTLSServer(t, &tlsConfig{Enable: true, CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.file})
+
+	tests := []struct {
+		name    string
+		certs   []tls.Certificate
+		want    string
+		wantErr bool
+	}{
+		{name: "missing client cert", wantErr: true},
+		{name: "untrusted client cert", certs: []tls.Certificate{newTestCA(t, t.TempDir()).issue(t, "other", 3, x509.ExtKeyUsageClientAuth, "", "")}, wantErr: true},
+		{name: "trusted client cert", certs: []tls.Certificate{ca.issue(t, "client", 4, x509.ExtKeyUsageClientAuth, "", "")}, want: "client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _, err := tlsGet(srv, ca.pool, tt.certs...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if body != tt.want {
+				t.Errorf("identity = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSCertRotation(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth, certFile, keyFile)
+	srv := newTLSServer(t, &tlsConfig{Enable: true, CertFile: certFile, KeyFile: keyFile})
+
+	if _, peer, err := tlsGet(srv, ca.pool); err != nil || peer.SerialNumber.Int64() != 2 {
+		t.Fatalf("initial cert = %v, %v, want serial 2", peer, err)
+	}
+	ca.issue(t, "server", 5, x509.ExtKeyUsageServerAuth, certFile, keyFile)
+	touch(t, certFile, keyFile)
+	time.Sleep(certCheckInterval + 100*time.Millisecond)
+
+	_, peer, err := tlsGet(srv, ca.pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := peer.SerialNumber.Int64(); got != 5 {
+		t.Errorf("serial after rotation = %d, want 5", got)
+	}
+}
+
+func TestCertReloaderBrokenRotation(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth, certFile, keyFile)
+	r := &certReloader{app: New(), cfg: &tlsConfig{CertFile: certFile, KeyFile: keyFile}, base: &tls.Config{}}
+	if err := r.load(); err != nil {
+		t.Fatal(err)
+	}
+	old := r.current
+
+	// 轮换为损坏的证书, 继续使用旧证书且不再反复加载
+	if err := os.WriteFile(certFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, certFile)
+	r.checked = time.Time{}
+	cfg, err := r.getConfigForClient(nil)
+	if err != nil || cfg != old {
+		t.Fatalf("getConfigForClient() = %v, %v, want previous config", cfg, err)
+	}
+	if !r.modTime.Equal(r.latestModTime()) {
+		t.Errorf("modTime = %s, want failed file time %s", r.modTime, r.latestModTime())
+	}
+}
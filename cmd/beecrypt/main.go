@@ -0,0 +1,71 @@
+// Command beecrypt 配置密文工具: 生成主密钥、加密/解密配置值
+//
+//	beecrypt -genkey
+//	BEE_MASTER_KEY=xxx beecrypt 明文
+//	beecrypt -key xxx -d enc:xxx
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dhlanshan/go-saillibs/bee"
+)
+
+func main() {
+	genKey := flag.Bool("genkey", false, "生成新的主密钥")
+	keyArg := flag.String("key", "", "主密钥(base64), 默认读取环境变量"+bee.EnvMasterKey)
+	keyFile := flag.String("keyfile", "", "主密钥文件路径")
+	decrypt := flag.Bool("d", false, "解密")
+	flag.Parse()
+
+	if *genKey {
+		key, err := bee.GenerateMasterKey()
+		exitOnErr(err)
+		fmt.Println(key)
+		return
+	}
+
+	encoded := *keyArg
+	if encoded == "" && *keyFile != "" {
+		content, err := os.ReadFile(*keyFile)
+		exitOnErr(err)
+		encoded = string(content)
+	}
+	if encoded == "" {
+		encoded = os.Getenv(bee.EnvMasterKey)
+	}
+	key, err := bee.ParseMasterKey(encoded)
+	exitOnErr(err)
+
+	// 未传参数时从标准输入逐行读取
+	values := flag.Args()
+	if len(values) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+				values = append(values, line)
+			}
+		}
+	}
+	for _, v := range values {
+		var out string
+		if *decrypt {
+			out, err = bee.DecryptValue(key, v)
+		} else {
+			out, err = bee.EncryptValue(key, v)
+		}
+		exitOnErr(err)
+		fmt.Println(out)
+	}
+}
+
+func exitOnErr(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -3,51 +3,197 @@ package db
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/dgraph-io/badger/v4"
-	"sync"
+	"github.com/dgraph-io/badger/v4/options"
+	"github.com/dhlanshan/go-saillibs/bee"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-var badgerSession = sync.Map{}
+// badgerConfig Badger整体配置
+type badgerConfig struct {
+	RootDir  string                       `mapstructure:"rootDir" default:"data"`                                         // 数据根目录, 各存储默认使用<rootDir>/<存储名>
+	LogLevel string                       `mapstructure:"logLevel" default:"warn" validate:"oneof=debug info warn error"` // Badger内部日志级别, 支持热加载
+	Stores   map[string]badgerStoreConfig `mapstructure:"stores"`                                                         // 各存储的配置, key为存储名
+}
+
+// badgerStoreConfig Badger存储配置, 修改后需重新打开存储生效
+type badgerStoreConfig struct {
+	Dir              string `mapstructure:"dir"`                                                            // 数据目录, 默认<rootDir>/<存储名>
+	InMemory         bool   `mapstructure:"inMemory"`                                                       // 内存模式, 数据不落盘
+	SyncWrites       bool   `mapstructure:"syncWrites"`                                                     // 每次写入同步刷盘
+	Compression      string `mapstructure:"compression" default:"snappy" validate:"oneof=none snappy zstd"` // 压缩算法 none/snappy/zstd
+	ValueLogFileSize int64  `mapstructure:"valueLogFileSize" validate:"min=0"`                              // 值日志文件大小(字节), 0使用默认值
+	BlockCacheSize   int64  `mapstructure:"blockCacheSize" validate:"min=0"`                                // 块缓存大小(字节), 0使用默认值
+	IndexCacheSize   int64  `mapstructure:"indexCacheSize" validate:"min=0"`                                // 索引缓存大小(字节), 启用加密时默认100M
+	EncryptionKey    string `mapstructure:"encryptionKey"`                                                  // 加密密钥, 长度16、24或32字节, 可使用enc:密文或${file:}引用
+	ReadOnly         bool   `mapstructure:"readOnly"`                                                       // 只读模式
+}
+
+var badgerCompression = map[string]options.CompressionType{
+	"none":   options.None,
+	"snappy": options.Snappy,
+	"zstd":   options.ZSTD,
+}
+
+// defaultIndexCacheSize 启用加密时的默认索引缓存大小
+const defaultIndexCacheSize = 100 << 20
+
+// badgerLogger Badger日志, 通过应用日志输出, 每次写入时获取日志以使用重建后的日志
+type badgerLogger struct {
+	log   func() *zap.SugaredLogger
+	store string
+	level zap.AtomicLevel
+}
+
+func (l *badgerLogger) logf(level zapcore.Level, format string, args ...any) {
+	if l.level.Enabled(level) {
+		l.log().Named("badger").With("store", l.store).Logf(level, strings.TrimRight(format, "\n"), args...)
+	}
+}
+
+func (l *badgerLogger) Errorf(format string, args ...any) {
+	l.logf(zapcore.ErrorLevel, format, args...)
+}
+
+func (l *badgerLogger) Warningf(format string, args ...any) {
+	l.logf(zapcore.WarnLevel, format, args...)
+}
+
+func (l *badgerLogger) Infof(format string, args ...any) {
+	l.logf(zapcore.InfoLevel, format, args...)
+}
+
+func (l *badgerLogger) Debugf(format string, args ...any) {
+	l.logf(zapcore.DebugLevel, format, args...)
+}
 
 // BadgerClient 数据库客户端
 type badgerClient struct {
-	RootDir string
+	Key   string                    // 配置前缀
+	conf  *viper.Viper              // 配置实例
+	log   func() *zap.SugaredLogger // 应用日志
+	level zap.AtomicLevel           // Badger日志级别
+}
+
+// options 按配置生成存储选项
+func (b badgerClient) options(dbName string) (badger.Options, error) {
+	cfg, err := bee.LoadConfigFrom[badgerConfig](b.conf, b.Key)
+	if err != nil {
+		return badger.Options{}, err
+	}
+	store, err := bee.DecodeConfig[badgerStoreConfig](fmt.Sprintf("%s.stores.%s", b.Key, dbName), b.conf.Get(fmt.Sprintf("%s.stores.%s", b.Key, dbName)))
+	if err != nil {
+		return badger.Options{}, err
+	}
+
+	dir := store.Dir
+	if dir == "" {
+		dir = filepath.Join(cfg.RootDir, dbName)
+	}
+	if store.InMemory {
+		dir = ""
+	}
+	opts := badger.DefaultOptions(dir).
+		WithInMemory(store.InMemory).
+		WithSyncWrites(store.SyncWrites).
+		WithCompression(badgerCompression[store.Compression]).
+		WithReadOnly(store.ReadOnly).
+		WithLogger(&badgerLogger{log: b.log, store: dbName, level: b.level})
+	if store.ValueLogFileSize > 0 {
+		opts = opts.WithValueLogFileSize(store.ValueLogFileSize)
+	}
+	if store.BlockCacheSize > 0 {
+		opts = opts.WithBlockCacheSize(store.BlockCacheSize)
+	}
+	if store.IndexCacheSize > 0 {
+		opts = opts.WithIndexCacheSize(store.IndexCacheSize)
+	}
+	if store.EncryptionKey != "" {
+		switch len(store.EncryptionKey) {
+		case 16, 24, 32:
+		default:
+			return badger.Options{}, fmt.Errorf("<%s.stores.%s.encryptionKey>长度需为16、24或32字节", b.Key, dbName)
+		}
+		opts = opts.WithEncryptionKey([]byte(store.EncryptionKey))
+		if store.IndexCacheSize == 0 {
+			opts = opts.WithIndexCacheSize(defaultIndexCacheSize)
+		}
+	}
+
+	return opts, nil
 }
 
 func (b badgerClient) initSession(dbName string) (*badger.DB, error) {
+	opts, err := b.options(dbName)
+	if err != nil {
+		return nil, err
+	}
 	// 打开新实例
-	db, err := badger.Open(badger.DefaultOptions(b.RootDir + "/" + dbName).WithLogger(nil))
+	db, err := badger.Open(opts)
 	if err != nil {
 		return nil, err
 	}
 	return db, nil
 }
 
+// GetBadgerClient 获取默认应用的Badger客户端
 func GetBadgerClient(dbName string) (bd *badger.DB, err error) {
-	bdClient, ok := badgerSession.Load(dbName)
+	return Default().GetBadgerClient(dbName)
+}
+
+// CloseAllBadgerClient 关闭默认应用的所有Badger客户端, 可直接用作ExitAfter, 关闭失败时输出错误
+func CloseAllBadgerClient() {
+	if err := CloseBadgerClients(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// CloseBadgerClients 关闭默认应用的所有Badger客户端, 返回关闭失败的错误
+func CloseBadgerClients() error {
+	return Default().CloseAllBadgerClient()
+}
+
+// GetBadgerClient 获取Badger客户端
+func (r *Registry) GetBadgerClient(dbName string) (bd *badger.DB, err error) {
+	bdClient, ok := r.badgerSession.Load(dbName)
 	if !ok {
-		bdClient, err = badgerClient{"data"}.initSession(dbName)
-		if err != nil {
-			return nil, err
+		// 加锁后再次检查, 同一目录只打开一次
+		r.badgerMu.Lock()
+		defer r.badgerMu.Unlock()
+		if bdClient, ok = r.badgerSession.Load(dbName); !ok {
+			bdClient, err = badgerClient{Key: r.badgerKey, conf: r.conf(), log: r.log, level: r.badgerLevel}.initSession(dbName)
+			if err != nil {
+				return nil, err
+			}
+			r.badgerSession.Store(dbName, bdClient)
 		}
-		badgerSession.Store(dbName, bdClient)
 	}
 	db, ok := bdClient.(*badger.DB)
 	if !ok {
 		return nil, errors.New("badger db error")
 	}
 	return db, nil
-
 }
 
-func CloseAllBadgerClient() {
-	badgerSession.Range(func(k, v interface{}) bool {
+// CloseAllBadgerClient 关闭所有Badger客户端
+func (r *Registry) CloseAllBadgerClient() error {
+	var errs []error
+	r.badgerSession.Range(func(k, v interface{}) bool {
 		if dbClient, ok := v.(*badger.DB); ok {
-			if err := dbClient.Close(); err == nil {
-				badgerSession.Delete(k)
-				fmt.Printf("数据库客户端连接已关闭: %s\n", k)
+			if err := dbClient.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("<%s>数据库连接关闭失败: %w", k, err))
+				return true
 			}
+			r.badgerSession.Delete(k)
+			fmt.Printf("数据库客户端连接已关闭: %s\n", k)
 		}
 		return true
 	})
+
+	return errors.Join(errs...)
 }
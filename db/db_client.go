@@ -3,69 +3,62 @@ package db
 import (
 	"errors"
 	"fmt"
-	"github.com/mitchellh/mapstructure"
+	"github.com/dhlanshan/go-saillibs/bee"
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
-	"sync"
 	"time"
 )
 
-var session = sync.Map{}
-
 // dBaseConfig 数据库整体配置
 type dBaseConfig struct {
-	IsOutLog        bool          `json:"isOutLog"`        // 是否打印SQL日志
-	MaxIdle         int           `json:"maxIdle"`         // 设置连接池中空闲连接的最大数量
-	MaxOpen         int           `json:"maxOpen"`         // 设置打开数据库连接的最大数量
-	ConnMaxLifetime time.Duration `json:"connMaxLifetime"` // 设置了连接可复用的最大时间
+	IsOutLog        bool          `mapstructure:"isOutLog"`                                       // 是否打印SQL日志
+	MaxIdle         int           `mapstructure:"maxIdle" default:"10" validate:"min=0"`          // 设置连接池中空闲连接的最大数量
+	MaxOpen         int           `mapstructure:"maxOpen" default:"100" validate:"min=1"`         // 设置打开数据库连接的最大数量
+	ConnMaxLifetime time.Duration `mapstructure:"connMaxLifetime" default:"1h" validate:"min=1s"` // 设置了连接可复用的最大时间
+	Log             gormLogConfig `mapstructure:"log"`                                            // SQL日志配置
 }
 
-// setDefault 设置默认参数
-func (c *dBaseConfig) setDefault() {
-	if c.MaxIdle == 0 {
-		c.MaxIdle = 10
-	}
-	if c.MaxOpen == 0 {
-		c.MaxOpen = 100
-	}
-	if c.ConnMaxLifetime == 0 {
-		c.ConnMaxLifetime = time.Hour
-	}
+// dialectConfig 数据库方言配置
+type dialectConfig struct {
+	Dialect string `mapstructure:"dialect" validate:"required,oneof=Mysql Postgresql"` // 引擎类型
 }
 
 // DataBaseClient 数据库客户端
 type dataBaseClient struct {
-	Key string // 配置前缀
+	Key    string       // 配置前缀
+	conf   *viper.Viper // 配置实例
+	logger *GormLogger  // SQL日志
 }
 
 func (c dataBaseClient) initSession(dbName string) (*gorm.DB, error) {
 	// 获取对应的数据库配置信息
 	dbKey := fmt.Sprintf("%s.%s", c.Key, dbName)
-	dbCfg := viper.GetStringMap(dbKey)
-	if len(dbCfg) == 0 {
+	if len(c.conf.GetStringMap(dbKey)) == 0 {
 		return nil, errors.New(fmt.Sprintf("未找到<%s>数据库配置信息", dbName))
 	}
+	dialectCfg, err := bee.LoadConfigFrom[dialectConfig](c.conf, dbKey)
+	if err != nil {
+		return nil, err
+	}
 
 	var dialect gorm.Dialector
-	dialectType := dbCfg["dialect"].(string)
-	if dialectType == "Mysql" {
-		_mysql := &mysqlClient{Key: dbKey}
-		dialect = _mysql.getDialect()
-	} else if dialectType == "Postgresql" {
-		_pgsql := &postgresqlClient{Key: dbKey}
-		dialect = _pgsql.getDialect()
+	if dialectCfg.Dialect == "Mysql" {
+		_mysql := &mysqlClient{Key: dbKey, conf: c.conf}
+		dialect, err = _mysql.getDialect()
 	} else {
-		return nil, errors.New(fmt.Sprintf("<%s>数据库方言类型错误", dbName))
+		_pgsql := &postgresqlClient{Key: dbKey, conf: c.conf}
+		dialect, err = _pgsql.getDialect()
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// gorm配置，整体数据库配置
-	var dbConfig dBaseConfig
-	if err := mapstructure.Decode(viper.GetStringMap(c.Key), &dbConfig); err != nil {
-		return nil, errors.New("数据库整体配置转换失败。")
+	dbConfig, err := bee.LoadConfigFrom[dBaseConfig](c.conf, c.Key)
+	if err != nil {
+		return nil, err
 	}
-	dbConfig.setDefault()
 
 	cfg := &gorm.Config{
 		SkipDefaultTransaction:                   false,
@@ -73,9 +66,9 @@ func (c dataBaseClient) initSession(dbName string) (*gorm.DB, error) {
 		DisableAutomaticPing:                     false,
 		DisableForeignKeyConstraintWhenMigrating: true, // 禁用创建外键约束
 	}
-	if dbConfig.IsOutLog {
-		cfg.Logger = logger.Default.LogMode(logger.Info) // 打印sql语句
-	}
+	// SQL日志通过应用日志输出, isOutLog为true时打印sql语句
+	c.logger.apply(dbConfig)
+	cfg.Logger = c.logger
 	db, err := gorm.Open(dialect, cfg)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("<%s>数据库连接错误", dbName))
@@ -93,15 +86,37 @@ func (c dataBaseClient) initSession(dbName string) (*gorm.DB, error) {
 	return db, nil
 }
 
-// GetDbClient 获取数据库客户端
+// GetDbClient 获取默认应用的数据库客户端
 func GetDbClient(dbName string) (db *gorm.DB, err error) {
-	dbClient, ok := session.Load(dbName)
+	return Default().GetDbClient(dbName)
+}
+
+// CloseAllDbClient 关闭默认应用的所有数据库客户端连接, 可直接用作ExitAfter, 关闭失败时输出错误
+func CloseAllDbClient() {
+	if err := CloseDbClients(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// CloseDbClients 关闭默认应用的所有数据库客户端连接, 返回关闭失败的错误
+func CloseDbClients() error {
+	return Default().CloseAllDbClient()
+}
+
+// GetDbClient 获取数据库客户端
+func (r *Registry) GetDbClient(dbName string) (db *gorm.DB, err error) {
+	dbClient, ok := r.session.Load(dbName)
 	if !ok {
-		dbClient, err = dataBaseClient{"dbClient"}.initSession(dbName)
-		if err != nil {
-			return nil, err
+		// 加锁后再次检查, 同一数据库只创建一个连接池
+		r.sessionMu.Lock()
+		defer r.sessionMu.Unlock()
+		if dbClient, ok = r.session.Load(dbName); !ok {
+			dbClient, err = dataBaseClient{Key: r.dbKey, conf: r.conf(), logger: r.sqlLogger}.initSession(dbName)
+			if err != nil {
+				return nil, err
+			}
+			r.session.Store(dbName, dbClient)
 		}
-		session.Store(dbName, dbClient)
 	}
 	db, ok = dbClient.(*gorm.DB)
 	if !ok {
@@ -112,15 +127,23 @@ func GetDbClient(dbName string) (db *gorm.DB, err error) {
 }
 
 // CloseAllDbClient 关闭所有数据库客户端连接
-func CloseAllDbClient() {
-	session.Range(func(k, v interface{}) bool {
+func (r *Registry) CloseAllDbClient() error {
+	var errs []error
+	r.session.Range(func(k, v interface{}) bool {
 		if dbClient, ok := v.(*gorm.DB); ok {
-			if db, err := dbClient.DB(); err == nil {
-				_ = db.Close()
-				session.Delete(k)
-				fmt.Printf("数据库客户端连接已关闭: %s\n", k)
+			db, err := dbClient.DB()
+			if err == nil {
+				err = db.Close()
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("<%s>数据库连接关闭失败: %w", k, err))
+				return true
 			}
+			r.session.Delete(k)
+			fmt.Printf("数据库客户端连接已关闭: %s\n", k)
 		}
 		return true
 	})
+
+	return errors.Join(errs...)
 }
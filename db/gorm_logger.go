@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dhlanshan/go-saillibs/bee"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// gormLogConfig SQL日志配置, 位于数据库整体配置的log下
+type gormLogConfig struct {
+	Level                string        `mapstructure:"level" validate:"oneof=silent error warn info"` // 日志级别 silent/error/warn/info, 为空时isOutLog为true取info否则取warn
+	SlowThreshold        time.Duration `mapstructure:"slowThreshold" default:"200ms"`                 // 慢查询阈值
+	IgnoreRecordNotFound bool          `mapstructure:"ignoreRecordNotFound"`                          // 忽略记录不存在错误
+	ParamsFilter         bool          `mapstructure:"paramsFilter"`                                  // 不记录SQL参数, 参数以?占位输出
+}
+
+var gormLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+// GormLogger GORM日志, 通过应用日志输出, 携带请求链路ID及调用位置
+type GormLogger struct {
+	log   func() *zap.SugaredLogger
+	cfg   *atomic.Pointer[gormLogConfig]
+	level logger.LogLevel // LogMode设置的级别, 0表示使用配置的级别
+}
+
+// NewGormLogger 创建GORM日志, log为空时使用默认应用的日志
+func NewGormLogger(log func() *zap.SugaredLogger) *GormLogger {
+	if log == nil {
+		log = func() *zap.SugaredLogger { return bee.Default().Logger() }
+	}
+	l := &GormLogger{log: log, cfg: new(atomic.Pointer[gormLogConfig])}
+	l.cfg.Store(&gormLogConfig{Level: "warn", SlowThreshold: 200 * time.Millisecond})
+	return l
+}
+
+// apply 应用数据库配置中的SQL日志配置
+func (l *GormLogger) apply(dbConfig *dBaseConfig) {
+	cfg := dbConfig.Log
+	if cfg.Level == "" {
+		cfg.Level = "warn"
+		if dbConfig.IsOutLog {
+			cfg.Level = "info"
+		}
+	}
+	l.cfg.Store(&cfg)
+}
+
+// logLevel 当前生效的级别
+func (l *GormLogger) logLevel() logger.LogLevel {
+	if l.level != 0 {
+		return l.level
+	}
+	return gormLevels[l.cfg.Load().Level]
+}
+
+// logger 携带链路ID的日志
+func (l *GormLogger) logger(ctx context.Context) *zap.SugaredLogger {
+	log := l.log().Named("gorm")
+	if traceID := bee.TraceID(ctx); traceID != "" {
+		log = log.With(bee.TraceIDKey, traceID)
+	}
+	return log
+}
+
+// LogMode 设置日志级别, 如db.Debug()
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	newLogger := *l
+	newLogger.level = level
+	return &newLogger
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...any) {
+	if l.logLevel() >= logger.Info {
+		l.logger(ctx).Infow(msgf(msg, data), "caller", sqlCaller())
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.logLevel() >= logger.Warn {
+		l.logger(ctx).Warnw(msgf(msg, data), "caller", sqlCaller())
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...any) {
+	if l.logLevel() >= logger.Error {
+		l.logger(ctx).Errorw(msgf(msg, data), "caller", sqlCaller())
+	}
+}
+
+// Trace 记录SQL执行: 错误、慢查询及普通查询
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	level := l.logLevel()
+	if level <= logger.Silent {
+		return
+	}
+	cfg := l.cfg.Load()
+	elapsed := time.Since(begin)
+	fields := func() []any {
+		sql, rows := fc()
+		return []any{"sql", sql, "rows", rows, "elapsed", elapsed.String(), "caller", sqlCaller()}
+	}
+
+	switch {
+	case err != nil && level >= logger.Error && !(cfg.IgnoreRecordNotFound && errors.Is(err, gorm.ErrRecordNotFound)):
+		l.logger(ctx).Errorw("SQL执行错误", append(fields(), "error", err.Error())...)
+	case cfg.SlowThreshold > 0 && elapsed > cfg.SlowThreshold && level >= logger.Warn:
+		l.logger(ctx).Warnw("慢查询", append(fields(), "threshold", cfg.SlowThreshold.String())...)
+	case level >= logger.Info:
+		l.logger(ctx).Infow("SQL", fields()...)
+	}
+}
+
+// ParamsFilter 开启paramsFilter时SQL日志不输出参数值
+func (l *GormLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.cfg.Load().ParamsFilter {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// sqlCaller 业务代码中发起SQL的位置, 跳过gorm及本文件的调用栈
+func sqlCaller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if !strings.Contains(f.File, "gorm.io/") && !strings.HasSuffix(f.File, "/db/gorm_logger.go") {
+			return f.File + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// msgf 格式化消息
+func msgf(msg string, data []any) string {
+	if len(data) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, data...)
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	"gorm.io/gorm"
+)
+
+// PingAll 检查所有数据库连接池
+func (r *Registry) PingAll(ctx context.Context) error {
+	var errs []error
+	r.session.Range(func(k, v interface{}) bool {
+		if dbClient, ok := v.(*gorm.DB); ok {
+			db, err := dbClient.DB()
+			if err == nil {
+				err = db.PingContext(ctx)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("<%s>%w", k, err))
+			}
+		}
+		return true
+	})
+
+	return errors.Join(errs...)
+}
+
+// CheckBadger 检查所有Badger实例是否可用
+func (r *Registry) CheckBadger(ctx context.Context) error {
+	var errs []error
+	r.badgerSession.Range(func(k, v interface{}) bool {
+		if bd, ok := v.(*badger.DB); ok {
+			if bd.IsClosed() {
+				errs = append(errs, fmt.Errorf("<%s>已关闭", k))
+				return true
+			}
+			if err := bd.View(func(txn *badger.Txn) error { return nil }); err != nil {
+				errs = append(errs, fmt.Errorf("<%s>%w", k, err))
+			}
+		}
+		return ctx.Err() == nil
+	})
+
+	return errors.Join(errs...)
+}
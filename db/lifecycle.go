@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+
+	"github.com/dhlanshan/go-saillibs/bee"
+)
+
+// DbComponent 数据库客户端生命周期组件, 停止时关闭默认应用的所有数据库连接
+func DbComponent() *bee.Component {
+	return &bee.Component{
+		Name: "db",
+		Stop: func(ctx context.Context) error {
+			return CloseDbClients()
+		},
+	}
+}
+
+// BadgerComponent Badger客户端生命周期组件, 停止时关闭默认应用的所有Badger实例
+func BadgerComponent() *bee.Component {
+	return &bee.Component{
+		Name: "badger",
+		Stop: func(ctx context.Context) error {
+			return CloseBadgerClients()
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/dhlanshan/go-saillibs/bee"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+)
+
+// registryKey 注册表在应用扩展中的key
+const registryKey = "db.registry"
+
+// Registry 数据库客户端注册表, 每个应用拥有独立的注册表
+type Registry struct {
+	conf          func() *viper.Viper // 配置实例
+	dbKey         string              // 数据库配置前缀key
+	badgerKey     string              // Badger配置前缀key
+	badgerLevel   zap.AtomicLevel     // Badger日志级别
+	log           func() *zap.SugaredLogger
+	sqlLogger     *GormLogger // SQL日志
+	session       sync.Map
+	sessionMu     sync.Mutex // 串行化数据库客户端的创建, 避免重复创建连接池
+	badgerSession sync.Map
+	badgerMu      sync.Mutex // 串行化Badger存储的打开, 避免重复打开同一目录
+}
+
+// NewRegistry 创建注册表, SQL及Badger日志使用默认应用的日志
+func NewRegistry(conf func() *viper.Viper) *Registry {
+	log := func() *zap.SugaredLogger { return bee.Default().Logger() }
+	return &Registry{
+		conf:        conf,
+		dbKey:       "dbClient",
+		badgerKey:   "badger",
+		badgerLevel: zap.NewAtomicLevelAt(zap.WarnLevel),
+		log:         log,
+		sqlLogger:   NewGormLogger(log),
+	}
+}
+
+// FromApp 获取应用的注册表, 首次获取时创建, 注册健康检查、订阅连接池配置并随应用关闭
+func FromApp(app *bee.MagicApp) *Registry {
+	return app.Extension(registryKey, func() any {
+		r := NewRegistry(app.Config)
+		r.log = app.Logger
+		r.sqlLogger = NewGormLogger(app.Logger)
+		if cfg, err := bee.LoadConfigFrom[badgerConfig](app.Config(), r.badgerKey); err == nil {
+			r.setBadgerLevel(cfg)
+		}
+		_ = app.Register(&bee.Component{
+			Name: registryKey,
+			Stop: func(ctx context.Context) error {
+				return r.Close()
+			},
+		})
+		// 连接池及SQL日志配置变更时调整所有连接
+		bee.WatchConfig(app, r.dbKey, func(_, new *dBaseConfig) {
+			r.resizePools(new)
+			r.sqlLogger.apply(new)
+		})
+		// Badger日志级别变更时直接调整, 存储选项需重新打开存储生效
+		bee.WatchConfig(app, r.badgerKey, func(_, new *badgerConfig) {
+			r.setBadgerLevel(new)
+		})
+		app.AddHealthCheck(
+			&bee.HealthCheck{Name: "db", Check: r.PingAll},
+			&bee.HealthCheck{Name: "badger", Check: r.CheckBadger},
+		)
+		return r
+	}).(*Registry)
+}
+
+// Default 默认应用的注册表
+func Default() *Registry {
+	return FromApp(bee.Default())
+}
+
+// Close 关闭注册表中的所有客户端, 数据库连接关闭失败时仍关闭Badger
+func (r *Registry) Close() error {
+	dbErr := r.CloseAllDbClient()
+	badgerErr := r.CloseAllBadgerClient()
+	return errors.Join(dbErr, badgerErr)
+}
+
+// setBadgerLevel 设置Badger日志级别
+func (r *Registry) setBadgerLevel(cfg *badgerConfig) {
+	if level, err := zapcore.ParseLevel(cfg.LogLevel); err == nil {
+		r.badgerLevel.SetLevel(level)
+	}
+}
+
+// resizePools 按新配置调整所有连接池
+func (r *Registry) resizePools(cfg *dBaseConfig) {
+	r.session.Range(func(k, v interface{}) bool {
+		if dbClient, ok := v.(*gorm.DB); ok {
+			if sqlDB, err := dbClient.DB(); err == nil {
+				sqlDB.SetMaxIdleConns(cfg.MaxIdle)
+				sqlDB.SetMaxOpenConns(cfg.MaxOpen)
+				sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+			}
+		}
+		return true
+	})
+}
@@ -2,16 +2,27 @@
 package mdw
 
 import (
+	"github.com/dhlanshan/go-saillibs/bee"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	corsAllowHeaders  = "Authorization, Content-Length, X-CSRF-Token, Token,session,X_Requested_With,Accept, Origin, Host, Connection, Accept-Encoding, Accept-Language,DNT, X-CustomHeader, Keep-Alive, User-Agent, X-Requested-With, If-Modified-Since, Cache-Control, Content-Type, Pragma"
+	corsAllowMethods  = "POST, GET, OPTIONS, PUT, DELETE,UPDATE"
+	corsExposeHeaders = "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers,Cache-Control,Content-Language,Content-Type,Expires,Last-Modified,Pragma,FooBar"
 )
 
 func Cors() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Headers", "Authorization, Content-Length, X-CSRF-Token, Token,session,X_Requested_With,Accept, Origin, Host, Connection, Accept-Encoding, Accept-Language,DNT, X-CustomHeader, Keep-Alive, User-Agent, X-Requested-With, If-Modified-Since, Cache-Control, Content-Type, Pragma")
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE,UPDATE")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers,Cache-Control,Content-Language,Content-Type,Expires,Last-Modified,Pragma,FooBar")
+		c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
+		c.Header("Access-Control-Allow-Methods", corsAllowMethods)
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		// 放行所有OPTIONS方法
@@ -22,3 +33,62 @@ func Cors() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// corsConfig 跨域配置
+type corsConfig struct {
+	AllowOrigins     []string `mapstructure:"allowOrigins" default:"*"` // 允许的来源, *表示全部
+	AllowHeaders     []string `mapstructure:"allowHeaders"`             // 允许的请求头, 为空时使用默认列表
+	AllowMethods     []string `mapstructure:"allowMethods"`             // 允许的方法, 为空时使用默认列表
+	ExposeHeaders    []string `mapstructure:"exposeHeaders"`            // 暴露的响应头, 为空时使用默认列表
+	AllowCredentials bool     `mapstructure:"allowCredentials"`         // 是否允许携带凭证
+	MaxAge           int      `mapstructure:"maxAge" validate:"min=0"`  // 预检结果缓存时间(秒)
+}
+
+// headerValue 列表转换为响应头, 为空时使用默认值
+func headerValue(items []string, def string) string {
+	if len(items) == 0 {
+		return def
+	}
+	return strings.Join(items, ", ")
+}
+
+// CorsFromConfig 基于配置的跨域中间件, 配置变更时自动生效, 配置不合法时返回错误
+func CorsFromConfig(app *bee.MagicApp, key string) (gin.HandlerFunc, error) {
+	var current atomic.Pointer[corsConfig]
+	cfg, err := bee.LoadConfigFrom[corsConfig](app.Config(), key)
+	if err != nil {
+		return nil, err
+	}
+	current.Store(cfg)
+	bee.WatchConfig(app, key, func(_, new *corsConfig) {
+		current.Store(new)
+	})
+
+	return func(c *gin.Context) {
+		cfg := current.Load()
+		origin := c.Request.Header.Get("Origin")
+		if slices.Contains(cfg.AllowOrigins, "*") {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else if origin != "" && slices.Contains(cfg.AllowOrigins, origin) {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
+		c.Header("Access-Control-Allow-Headers", headerValue(cfg.AllowHeaders, corsAllowHeaders))
+		c.Header("Access-Control-Allow-Methods", headerValue(cfg.AllowMethods, corsAllowMethods))
+		c.Header("Access-Control-Expose-Headers", headerValue(cfg.ExposeHeaders, corsExposeHeaders))
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if cfg.MaxAge > 0 {
+			c.Header("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+		}
+
+		// 放行所有OPTIONS方法
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}, nil
+}
@@ -15,7 +15,7 @@ func ExceptionMiddleware() gin.HandlerFunc {
 				} else {
 					bee.ErrorJsonResponse(c, bee.SystemErr, "系统错误。")
 				}
-				bee.Logger.Error(err)
+				bee.Log(c).Error(err)
 				c.Abort()
 			}
 		}()
@@ -9,65 +9,174 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"io"
+	"math/rand"
+	"net/http"
 	"time"
 )
 
+// captureMargin 响应内容储存上限超出MaxBody的余量, 脱敏可能缩短内容
+const captureMargin = 1024
+
 type CustomResponseWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body  *bytes.Buffer
+	limit int // 最多储存的字节数, 0表示不限制
 }
 
 func (w CustomResponseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	w.body.Write(b[:w.captured(len(b))])
 	return w.ResponseWriter.Write(b)
 }
 
 func (w CustomResponseWriter) WriteString(s string) (int, error) {
-	w.body.WriteString(s)
+	w.body.WriteString(s[:w.captured(len(s))])
 	return w.ResponseWriter.WriteString(s)
 }
 
+// captured 本次写入的n字节中需储存的字节数
+func (w CustomResponseWriter) captured(n int) int {
+	if w.limit <= 0 {
+		return n
+	}
+	return min(n, max(w.limit-w.body.Len(), 0))
+}
+
+// validTraceID 上游传入的链路ID是否合法: 1~128位字母、数字及-_.
+func validTraceID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch ch := id[i]; {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9', ch == '-', ch == '_', ch == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 type LogMWCmd struct {
-	NotReqBodyRoute  []string // 不记录请求内容的路由列表
-	NotRespBodyRoute []string // 不记录响应内容的路由列表
+	NotReqBodyRoute  []string           // 不记录请求内容的路由列表
+	NotRespBodyRoute []string           // 不记录响应内容的路由列表
+	SampleRates      map[string]float64 // 路由访问日志采样率(0~1), key为路由如/api/user/:id, 未配置的路由全部记录, 响应状态>=500时总会记录
+	Redact           *Redactor          // 脱敏规则, 为空时使用DefaultRedactor
+}
+
+// sampled 当前请求的访问日志是否采样记录
+func (cmd *LogMWCmd) sampled(c *gin.Context) bool {
+	if len(cmd.SampleRates) == 0 {
+		return true
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	rate, ok := cmd.SampleRates[route]
+	return !ok || rate >= 1 || rand.Float64() < rate
+}
+
+// peekBody 读取请求内容的前limit字节并恢复request.Body, limit为0时全部读取
+// 返回读取的内容及原始内容字节数, 超出limit且未知长度时原始字节数按已读取的计算
+func peekBody(req *http.Request, limit int) ([]byte, int) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, 0
+	}
+	var reader io.Reader = req.Body
+	if limit > 0 {
+		reader = io.LimitReader(req.Body, int64(limit)+1)
+	}
+	body, _ := io.ReadAll(reader)
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	total := len(body)
+	if limit > 0 && len(body) > limit {
+		body = body[:limit]
+		total = max(total, int(req.ContentLength))
+	}
+
+	return body, total
 }
 
 // LogMiddleware 日志
 func LogMiddleware(cmd *LogMWCmd) gin.HandlerFunc {
+	redact := cmd.Redact
+	if redact == nil {
+		redact = DefaultRedactor()
+	}
 	return func(c *gin.Context) {
 		startTime := time.Now().UnixMilli()
-		// 重写request.Body方法使其支持重读
-		reqBodyBytes, _ := c.GetRawData()
-		if len(reqBodyBytes) > 0 {
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBodyBytes))
+		// 读取请求内容用于记录并恢复request.Body, 最多读取MaxBody及余量, 大文件上传不整体缓存
+		reqExcluded := cmd.NotReqBodyRoute != nil && tools.InSlice[string](cmd.NotReqBodyRoute, c.Request.RequestURI)
+		var reqBodyBytes []byte
+		var reqBodySize int
+		if !reqExcluded {
+			limit := 0
+			if redact.MaxBody > 0 {
+				limit = redact.MaxBody + captureMargin
+			}
+			reqBodyBytes, reqBodySize = peekBody(c.Request, limit)
+		}
+		// 重写response使其支持储存, 不记录响应内容的路由不储存, 其余最多储存MaxBody及余量
+		respExcluded := cmd.NotRespBodyRoute != nil && tools.InSlice[string](cmd.NotRespBodyRoute, c.Request.RequestURI)
+		var blw *CustomResponseWriter
+		if !respExcluded {
+			blw = &CustomResponseWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+			if redact.MaxBody > 0 {
+				blw.limit = redact.MaxBody + captureMargin
+			}
+			c.Writer = blw
 		}
-		// 重写response使其支持储存
-		blw := &CustomResponseWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
 
 		// 记录API请求日志 格式："[Api] | 唯一ID | GET | url | header | body | END"
-		msgId := fmt.Sprintf("A%s", uuid.New().String())
-		// 将消息ID加入到上下文中
-		c.Set("trace_id", msgId)
+		// 优先沿用上游传入的合法链路ID
+		msgId := c.GetHeader(bee.TraceHeader)
+		if !validTraceID(msgId) {
+			msgId = fmt.Sprintf("A%s", uuid.New().String())
+		}
+		// 将消息ID加入到上下文中, 并传递到context.Context供下游调用使用
+		c.Set(bee.TraceIDKey, msgId)
+		release := bee.BindContext(c)
+		defer release()
+		c.Header(bee.TraceHeader, msgId)
 
-		header, _ := json.Marshal(c.Request.Header)
+		logger := bee.Log(c)
+		header, _ := json.Marshal(redact.Header(c.Request.Header))
 		msgFormat := "[Api] | %s | %s | %s | Header:%s | Body:%s | END"
-		if cmd.NotReqBodyRoute != nil && tools.InSlice[string](cmd.NotReqBodyRoute, c.Request.RequestURI) {
-			bee.Logger.Info(fmt.Sprintf(msgFormat, msgId, c.Request.Method, c.Request.RequestURI, header, "当前接口不记录请求内容"))
+		var reqMsg string
+		if reqExcluded {
+			reqMsg = fmt.Sprintf(msgFormat, msgId, c.Request.Method, c.Request.RequestURI, header, "当前接口不记录请求内容")
 		} else {
-			bee.Logger.Info(fmt.Sprintf(msgFormat, msgId, c.Request.Method, c.Request.RequestURI, header, string(reqBodyBytes)))
+			reqMsg = fmt.Sprintf(msgFormat, msgId, c.Request.Method, c.Request.RequestURI, header, redact.content(c.GetHeader("Content-Type"), reqBodyBytes, reqBodySize))
+		}
+		// 未采样的请求在响应异常时补记请求日志
+		sampled := cmd.sampled(c)
+		if sampled {
+			logger.Info(reqMsg)
 		}
 
-		// 执行请求处理程序和其他中间件
+		// 执行请求处理程序和其他中间件, 之后的日志携带鉴权中间件写入的用户及租户
 		c.Next()
+		logger = bee.Log(c)
+		if !sampled {
+			if c.Writer.Status() < http.StatusInternalServerError {
+				bee.AppFrom(c).Counter("bee_access_log_dropped_total", "Access log entries dropped by route sampling.").Inc()
+				return
+			}
+			logger.Info(reqMsg)
+		}
 		// 记录API响应日志 格式："[Api] | 唯一ID | 状态 | 内容 | 耗时 | END"
 		endTime := time.Now().UnixMilli()
 		eTime := fmt.Sprintf("%.5fs", (float64(endTime-startTime))*0.001)
 		//记录json响应
 		msgFormat = "[Api] | %s | 响应状态: %d | RespBody: %s | 耗时:%s | END"
-		if cmd.NotRespBodyRoute != nil && tools.InSlice[string](cmd.NotRespBodyRoute, c.Request.RequestURI) {
-			bee.Logger.Info(fmt.Sprintf(msgFormat, msgId, c.Writer.Status(), "当前接口不记录响应内容", eTime))
+		if respExcluded {
+			logger.Info(fmt.Sprintf(msgFormat, msgId, c.Writer.Status(), "当前接口不记录响应内容", eTime))
 		} else {
-			bee.Logger.Info(fmt.Sprintf(msgFormat, msgId, c.Writer.Status(), blw.body.String(), eTime))
+			respBody := redact.content(c.Writer.Header().Get("Content-Type"), blw.body.Bytes(), max(c.Writer.Size(), blw.body.Len()))
+			logger.Info(fmt.Sprintf(msgFormat, msgId, c.Writer.Status(), respBody, eTime))
 		}
 	}
 }
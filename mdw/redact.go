@@ -0,0 +1,228 @@
+package mdw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 常用脱敏正则
+var (
+	PhonePattern  = regexp.MustCompile(`\b1[3-9]\d{9}\b`)                  // 手机号
+	IDCardPattern = regexp.MustCompile(`\b\d{6}(?:19|20)\d{9}[\dXx]\b`)    // 身份证号
+	EmailPattern  = regexp.MustCompile(`\b[\w.+-]+@[\w-]+(?:\.[\w-]+)+\b`) // 邮箱
+)
+
+// defaultMask 默认脱敏掩码
+const defaultMask = "******"
+
+// formContentType 表单内容类型
+const formContentType = "application/x-www-form-urlencoded"
+
+// Redactor 日志脱敏规则
+type Redactor struct {
+	Headers  []string         // 脱敏的请求头, 不区分大小写
+	Fields   []string         // 脱敏的JSON及表单字段路径, 不含.时匹配任意层级的同名字段, 含.时从根开始匹配, *匹配任意一级, 数组不占层级, 如password、data.idCard、data.*.phone
+	Patterns []*regexp.Regexp // 正则脱敏, 匹配内容保留首3位及末4位
+	MaxBody  int              // 请求/响应内容最大记录字节数, 超出部分截断, 0表示不限制
+	Mask     string           // 掩码, 默认******
+}
+
+// DefaultRedactor 默认脱敏规则: 鉴权相关请求头, 密码、令牌字段, 身份证号及手机号, 内容最多记录4K
+func DefaultRedactor() *Redactor {
+	return &Redactor{
+		Headers:  []string{"Authorization", "Proxy-Authorization", "Token", "Cookie", "Set-Cookie", "X-Api-Key"},
+		Fields:   []string{"password", "passwd", "token", "secret"},
+		Patterns: []*regexp.Regexp{IDCardPattern, PhonePattern},
+		MaxBody:  4096,
+	}
+}
+
+// mask 掩码
+func (r *Redactor) mask() string {
+	if r.Mask == "" {
+		return defaultMask
+	}
+	return r.Mask
+}
+
+// Header 脱敏请求头
+func (r *Redactor) Header(header http.Header) http.Header {
+	result := header.Clone()
+	for _, name := range r.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := result[name]; ok {
+			result[name] = []string{r.mask()}
+		}
+	}
+	return result
+}
+
+// Body 脱敏请求/响应内容: JSON字段、正则匹配内容, 最后按长度截断
+func (r *Redactor) Body(body []byte) string {
+	return r.finish(r.redactJSON(body), 0)
+}
+
+// Form 脱敏表单(application/x-www-form-urlencoded)内容: 表单字段、正则匹配内容, 最后按长度截断
+func (r *Redactor) Form(body []byte) string {
+	return r.finish(r.redactForm(body), 0)
+}
+
+// content 按内容类型脱敏, total为原始内容字节数, 内容仅储存了前一部分时大于len(body)
+func (r *Redactor) content(contentType string, body []byte, total int) string {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == formContentType {
+		return r.finish(r.redactForm(body), total-len(body))
+	}
+	return r.finish(r.redactJSON(body), total-len(body))
+}
+
+// redactJSON 脱敏JSON字段, 内容不完整无法解析时按字段名匹配脱敏
+func (r *Redactor) redactJSON(body []byte) string {
+	if len(r.Fields) == 0 || len(body) == 0 || (body[0] != '{' && body[0] != '[') {
+		return string(body)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var data any
+	if decoder.Decode(&data) != nil {
+		return r.redactRawJSON(string(body))
+	}
+	data = r.redactValue(data, nil)
+	b, err := json.Marshal(data)
+	if err != nil {
+		return string(body)
+	}
+	return string(b)
+}
+
+// redactRawJSON 按字段名(路径的最后一级)脱敏无法解析的JSON, 宁可多脱敏
+func (r *Redactor) redactRawJSON(text string) string {
+	names := make([]string, 0, len(r.Fields))
+	for _, field := range r.Fields {
+		if name := field[strings.LastIndex(field, ".")+1:]; name != "*" {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+	}
+	if len(names) == 0 {
+		return text
+	}
+	return rawFieldPattern(strings.Join(names, "|")).ReplaceAllString(text, `${1}"`+strings.ReplaceAll(r.mask(), "$", "$$")+`"`)
+}
+
+// rawFieldPatterns 按字段名编译的正则缓存, key为|连接的字段名
+var rawFieldPatterns sync.Map
+
+// rawFieldPattern 获取匹配字段名及其值的正则, 同一组字段名只编译一次
+func rawFieldPattern(names string) *regexp.Regexp {
+	if re, ok := rawFieldPatterns.Load(names); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(`("(?i:` + names + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	actual, _ := rawFieldPatterns.LoadOrStore(names, re)
+	return actual.(*regexp.Regexp)
+}
+
+// redactForm 脱敏表单字段, 保留原有顺序及编码, 掩码不编码便于阅读
+func (r *Redactor) redactForm(body []byte) string {
+	if len(r.Fields) == 0 || len(body) == 0 {
+		return string(body)
+	}
+	pairs := strings.Split(string(body), "&")
+	for i, pair := range pairs {
+		key, _, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if r.matchField(strings.Split(name, ".")) {
+			pairs[i] = key + "=" + r.mask()
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// finish 正则脱敏并按长度截断, truncated为储存时已截断的字节数
+func (r *Redactor) finish(text string, truncated int) string {
+	for _, p := range r.Patterns {
+		text = p.ReplaceAllStringFunc(text, r.partialMask)
+	}
+	if r.MaxBody > 0 && len(text) > r.MaxBody {
+		// 截断位置回退到完整字符
+		end := r.MaxBody
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		truncated += len(text) - end
+		text = text[:end]
+	}
+	if truncated > 0 {
+		text = fmt.Sprintf("%s...(已截断%d字节)", text, truncated)
+	}
+
+	return text
+}
+
+// partialMask 保留首3位及末4位, 过短时全部掩码
+func (r *Redactor) partialMask(s string) string {
+	if len(s) <= 8 {
+		return r.mask()
+	}
+	return s[:3] + r.mask() + s[len(s)-4:]
+}
+
+// redactValue 递归脱敏JSON值, path为当前字段路径
+func (r *Redactor) redactValue(v any, path []string) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			p := append(path[:len(path):len(path)], k)
+			if r.matchField(p) {
+				val[k] = r.mask()
+				continue
+			}
+			val[k] = r.redactValue(item, p)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = r.redactValue(item, path)
+		}
+	}
+	return v
+}
+
+// matchField 字段路径是否需要脱敏
+func (r *Redactor) matchField(path []string) bool {
+	for _, field := range r.Fields {
+		if !strings.Contains(field, ".") {
+			if field == "*" || strings.EqualFold(field, path[len(path)-1]) {
+				return true
+			}
+			continue
+		}
+		segments := strings.Split(field, ".")
+		if len(segments) != len(path) {
+			continue
+		}
+		matched := true
+		for i, s := range segments {
+			if s != "*" && !strings.EqualFold(s, path[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}